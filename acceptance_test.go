@@ -19,6 +19,8 @@ import (
 	"testing"
 
 	"github.com/conduitio-labs/conduit-connector-nats-jetstream/config"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/message"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/source"
 	"github.com/conduitio-labs/conduit-connector-nats-jetstream/test"
 	sdk "github.com/conduitio/conduit-connector-sdk"
 	"github.com/google/uuid"
@@ -32,8 +34,10 @@ type driver struct {
 
 func (d driver) GenerateRecord(t *testing.T, operation sdk.Operation) sdk.Record {
 	record := d.ConfigurableAcceptanceTestDriver.GenerateRecord(t, operation)
-	// we don't need key for NATS JetStream
-	record.Key = nil
+	// NATS trims leading and trailing spaces of header values, so keys, which are sent in a header, lose them
+	if key, ok := record.Key.(sdk.RawData); ok {
+		record.Key = sdk.RawData(strings.TrimSpace(string(key)))
+	}
 
 	return record
 }
@@ -44,13 +48,19 @@ func TestAcceptance(t *testing.T) {
 		config.KeyURLs: test.TestURL,
 	}
 
+	// the destination writes record keys into a header, so the source takes them from there
+	sourceCfg := map[string]string{
+		config.KeyURLs:            test.TestURL,
+		source.ConfigKeyKeySource: "header:" + message.HeaderKey,
+	}
+
 	sdk.AcceptanceTest(t, driver{
 		ConfigurableAcceptanceTestDriver: sdk.ConfigurableAcceptanceTestDriver{
 			Config: sdk.ConfigurableAcceptanceTestDriverConfig{
 				Connector:         Connector,
-				SourceConfig:      cfg,
+				SourceConfig:      sourceCfg,
 				DestinationConfig: cfg,
-				BeforeTest:        beforeTest(cfg),
+				BeforeTest:        beforeTest(cfg, sourceCfg),
 				GoleakOptions: []goleak.Option{
 					// nats.go spawns a separate goroutine to process flush requests
 					// and we have no chance to stop it using the library's API
@@ -64,7 +74,7 @@ func TestAcceptance(t *testing.T) {
 }
 
 // beforeTest creates new stream before each test.
-func beforeTest(cfg map[string]string) func(t *testing.T) {
+func beforeTest(cfg, sourceCfg map[string]string) func(t *testing.T) {
 	return func(t *testing.T) {
 		is := is.New(t)
 
@@ -78,5 +88,6 @@ func beforeTest(cfg map[string]string) func(t *testing.T) {
 		is.NoErr(err)
 
 		cfg[config.KeySubject] = subject
+		sourceCfg[config.KeySubject] = subject
 	}
 }
@@ -0,0 +1,51 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/nats-io/nats.go"
+)
+
+// GetConnectionEventOptions returns connection options
+// which log disconnect, reconnect, closed and lame duck mode events.
+// The onClosed function, if not nil, is called with the last connection error
+// when the connection is closed.
+func GetConnectionEventOptions(ctx context.Context, onClosed func(err error)) []nats.Option {
+	logger := sdk.Logger(ctx)
+
+	return []nats.Option{
+		nats.DisconnectErrHandler(func(conn *nats.Conn, err error) {
+			logger.Warn().Err(err).Str("url", conn.ConnectedUrl()).Msg("disconnected from NATS")
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			logger.Info().Str("url", conn.ConnectedUrl()).Msg("reconnected to NATS")
+		}),
+		nats.LameDuckModeHandler(func(conn *nats.Conn) {
+			logger.Warn().Str("url", conn.ConnectedUrl()).Msg("NATS server entered lame duck mode")
+		}),
+		nats.ClosedHandler(func(conn *nats.Conn) {
+			err := conn.LastError()
+
+			logger.Info().Err(err).Msg("NATS connection closed")
+
+			if onClosed != nil {
+				onClosed(err)
+			}
+		}),
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/config"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/nats-io/nats.go"
+)
+
+// ProvisionStream creates the stream if it doesn't exist.
+// If the stream exists, its config is compared with the fields of the desired one which were set explicitly,
+// and the differences are either applied, if the update is allowed, or returned as an error.
+// It does nothing if the stream name is not set.
+func ProvisionStream(ctx context.Context, conn *nats.Conn, stream config.StreamConfig) error {
+	if stream.Name == "" {
+		return nil
+	}
+
+	jetstream, err := conn.JetStream()
+	if err != nil {
+		return fmt.Errorf("get jetstream context: %w", err)
+	}
+
+	logger := sdk.Logger(ctx).With().Str("stream", stream.Name).Logger()
+
+	desired := stream.ToNATS()
+
+	info, err := jetstream.StreamInfo(stream.Name, nats.Context(ctx))
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		if _, err := jetstream.AddStream(desired, nats.Context(ctx)); err != nil {
+			return fmt.Errorf("create stream %q: %w", stream.Name, err)
+		}
+
+		logger.Info().Strs("subjects", desired.Subjects).Msg("created stream")
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("get stream %q info: %w", stream.Name, err)
+	}
+
+	drift := StreamDrift(info.Config, stream)
+	if len(drift) == 0 {
+		return nil
+	}
+
+	if !stream.Update {
+		return fmt.Errorf("stream %q differs from the config: %s; set %q to true to update it",
+			stream.Name, strings.Join(drift, "; "), config.KeyStreamUpdate)
+	}
+
+	updated := mergeStreamConfig(info.Config, stream)
+
+	if _, err := jetstream.UpdateStream(&updated, nats.Context(ctx)); err != nil {
+		return fmt.Errorf("update stream %q (%s): %w", stream.Name, strings.Join(drift, "; "), err)
+	}
+
+	logger.Info().Strs("drift", drift).Msg("updated stream")
+
+	return nil
+}
+
+// StreamDrift returns descriptions of the differences between the actual stream config and the desired one,
+// only the fields which were set explicitly are compared.
+func StreamDrift(actual nats.StreamConfig, stream config.StreamConfig) []string {
+	var drift []string
+
+	desired := stream.ToNATS()
+
+	diff := func(key string, actual, desired any) {
+		if !stream.IsSet(key) {
+			return
+		}
+
+		if fmt.Sprint(actual) != fmt.Sprint(desired) {
+			drift = append(drift, fmt.Sprintf("%q is %v in the stream, but %v in the config", key, actual, desired))
+		}
+	}
+
+	diff(config.KeyStreamSubjects, sortedCopy(actual.Subjects), sortedCopy(desired.Subjects))
+	diff(config.KeyStreamRetention, actual.Retention, desired.Retention)
+	diff(config.KeyStreamStorage, actual.Storage, desired.Storage)
+	diff(config.KeyStreamReplicas, actual.Replicas, desired.Replicas)
+	diff(config.KeyStreamMaxAge, actual.MaxAge, desired.MaxAge)
+	diff(config.KeyStreamMaxBytes, actual.MaxBytes, desired.MaxBytes)
+	diff(config.KeyStreamDuplicateWindow, actual.Duplicates, desired.Duplicates)
+	diff(config.KeyStreamDiscard, actual.Discard, desired.Discard)
+
+	return drift
+}
+
+// mergeStreamConfig returns the actual stream config with the fields which were set explicitly
+// in the desired one, other fields are kept as they are.
+func mergeStreamConfig(actual nats.StreamConfig, stream config.StreamConfig) nats.StreamConfig {
+	desired := stream.ToNATS()
+
+	merged := actual
+
+	if stream.IsSet(config.KeyStreamSubjects) {
+		merged.Subjects = desired.Subjects
+	}
+
+	if stream.IsSet(config.KeyStreamRetention) {
+		merged.Retention = desired.Retention
+	}
+
+	if stream.IsSet(config.KeyStreamStorage) {
+		merged.Storage = desired.Storage
+	}
+
+	if stream.IsSet(config.KeyStreamReplicas) {
+		merged.Replicas = desired.Replicas
+	}
+
+	if stream.IsSet(config.KeyStreamMaxAge) {
+		merged.MaxAge = desired.MaxAge
+	}
+
+	if stream.IsSet(config.KeyStreamMaxBytes) {
+		merged.MaxBytes = desired.MaxBytes
+	}
+
+	if stream.IsSet(config.KeyStreamDuplicateWindow) {
+		merged.Duplicates = desired.Duplicates
+	}
+
+	if stream.IsSet(config.KeyStreamDiscard) {
+		merged.Discard = desired.Discard
+	}
+
+	return merged
+}
+
+// sortedCopy returns a sorted copy of the strings, so that their order doesn't matter when they're compared.
+func sortedCopy(strs []string) []string {
+	sorted := make([]string, len(strs))
+	copy(sorted, strs)
+	sort.Strings(sorted)
+
+	return sorted
+}
+
+// StreamParameters returns the parameters of a provisioned stream, shared between the source and the destination.
+func StreamParameters() map[string]sdk.Parameter {
+	return map[string]sdk.Parameter{
+		config.KeyStreamName: {
+			Default:  "",
+			Required: false,
+			Description: "A name of a stream the connector creates if it doesn't exist, or verifies otherwise. " +
+				"The stream is not provisioned if it's empty. Used in the jetstream mode.",
+		},
+		config.KeyStreamSubjects: {
+			Default:     "",
+			Required:    false,
+			Description: "A comma-separated list of subjects the provisioned stream covers, defaults to the subject.",
+		},
+		config.KeyStreamRetention: {
+			Default:  "limits",
+			Required: false,
+			Description: "A retention policy of the provisioned stream. " +
+				"Allowed values are limits, interest and workqueue.",
+		},
+		config.KeyStreamStorage: {
+			Default:     "file",
+			Required:    false,
+			Description: "A storage type of the provisioned stream. Allowed values are file and memory.",
+		},
+		config.KeyStreamReplicas: {
+			Default:     "1",
+			Required:    false,
+			Description: "A number of replicas of the provisioned stream.",
+		},
+		config.KeyStreamMaxAge: {
+			Default:     "",
+			Required:    false,
+			Description: "A max age of messages in the provisioned stream, messages don't expire if it's empty.",
+		},
+		config.KeyStreamMaxBytes: {
+			Default:     "-1",
+			Required:    false,
+			Description: "A max size of the provisioned stream in bytes, -1 means unlimited.",
+		},
+		config.KeyStreamDuplicateWindow: {
+			Default:  "",
+			Required: false,
+			Description: "A window the provisioned stream discards messages with duplicate IDs within, " +
+				"the server default is used if it's empty.",
+		},
+		config.KeyStreamDiscard: {
+			Default:  "old",
+			Required: false,
+			Description: "Defines which messages the provisioned stream discards once it reaches its limits. " +
+				"Allowed values are old and new.",
+		},
+		config.KeyStreamUpdate: {
+			Default:  "false",
+			Required: false,
+			Description: "Makes the connector update an existing stream which differs from the stream.* parameters, " +
+				"otherwise the connector fails to start and reports the differences.",
+		},
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/config"
+	"github.com/nats-io/nats.go"
+)
+
+// parseStreamConfig parses the stream config from the stream.* fields.
+func parseStreamConfig(t *testing.T, fields map[string]string) config.StreamConfig {
+	t.Helper()
+
+	cfg := map[string]string{
+		config.KeyURLs:       "nats://127.0.0.1:4222",
+		config.KeySubject:    "orders.eu",
+		config.KeyStreamName: "orders",
+	}
+
+	for key, value := range fields {
+		cfg[key] = value
+	}
+
+	parsed, err := config.Parse(cfg)
+	if err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+
+	return parsed.Stream
+}
+
+func TestStreamDrift(t *testing.T) {
+	t.Parallel()
+
+	actual := nats.StreamConfig{
+		Name:       "orders",
+		Subjects:   []string{"orders.eu", "orders.us"},
+		Retention:  nats.LimitsPolicy,
+		Storage:    nats.FileStorage,
+		Replicas:   1,
+		MaxAge:     time.Hour,
+		MaxBytes:   1 << 20,
+		Duplicates: 2 * time.Minute,
+		Discard:    nats.DiscardOld,
+	}
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   int
+	}{
+		{
+			name: "only the name is set, the defaults are not compared",
+			want: 0,
+		},
+		{
+			name: "same config, different order of subjects",
+			fields: map[string]string{
+				config.KeyStreamSubjects: "orders.us, orders.eu",
+				config.KeyStreamMaxAge:   "1h",
+				config.KeyStreamMaxBytes: "1048576",
+			},
+			want: 0,
+		},
+		{
+			name: "different max age and subjects",
+			fields: map[string]string{
+				config.KeyStreamSubjects: "orders.eu",
+				config.KeyStreamMaxAge:   "2h",
+			},
+			want: 2,
+		},
+		{
+			name: "different duplicate window",
+			fields: map[string]string{
+				config.KeyStreamDuplicateWindow: "1h",
+			},
+			want: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			drift := StreamDrift(actual, parseStreamConfig(t, tt.fields))
+			if len(drift) != tt.want {
+				t.Errorf("StreamDrift() = %v, want %d differences", drift, tt.want)
+			}
+		})
+	}
+}
+
+func Test_mergeStreamConfig(t *testing.T) {
+	t.Parallel()
+
+	actual := nats.StreamConfig{
+		Name:      "orders",
+		Subjects:  []string{"orders.eu"},
+		Retention: nats.LimitsPolicy,
+		Storage:   nats.FileStorage,
+		Replicas:  1,
+		MaxAge:    time.Hour,
+		MaxBytes:  1 << 20,
+		Discard:   nats.DiscardNew,
+	}
+
+	// only the subjects are set, so the other fields are kept
+	merged := mergeStreamConfig(actual, parseStreamConfig(t, map[string]string{
+		config.KeyStreamSubjects: "orders.eu, orders.us",
+	}))
+
+	want := actual
+	want.Subjects = []string{"orders.eu", "orders.us"}
+
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("mergeStreamConfig() = %v, want %v", merged, want)
+	}
+}
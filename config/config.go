@@ -22,6 +22,21 @@ import (
 
 	"github.com/conduitio-labs/conduit-connector-nats-jetstream/validator"
 	"github.com/google/uuid"
+	"go.uber.org/multierr"
+)
+
+// Mode defines a communication model the connector exchanges messages with.
+type Mode int
+
+const (
+	// ModeJetStream exchanges messages with a JetStream stream.
+	ModeJetStream Mode = iota
+	// ModeKV exchanges entries with a JetStream Key-Value bucket.
+	ModeKV
+	// ModeObject exchanges objects with a JetStream Object Store bucket.
+	ModeObject
+	// ModeCore exchanges messages with plain core NATS subjects, without JetStream.
+	ModeCore
 )
 
 const (
@@ -38,6 +53,14 @@ const (
 	KeyURLs = "urls"
 	// KeySubject is a config name for a subject.
 	KeySubject = "subject"
+	// KeySubjectTemplate is a config name for a template of subjects.
+	KeySubjectTemplate = "subjectTemplate"
+	// KeyMode is a config name for a communication model.
+	KeyMode = "mode"
+	// KeyKVBucket is a config name for a Key-Value bucket name.
+	KeyKVBucket = "kv.bucket"
+	// KeyObjectBucket is a config name for an Object Store bucket name.
+	KeyObjectBucket = "object.bucket"
 	// KeyConnectionName is a config name for a connection name.
 	KeyConnectionName = "connectionName"
 	// KeyNKeyPath is a config name for a path pointed to a NKey pair.
@@ -59,8 +82,19 @@ const (
 // Config contains configurable values
 // shared between source and destination NATS JetStream connector.
 type Config struct {
-	URLs    []string `key:"urls" validate:"required,dive,url"`
-	Subject string   `key:"subject" validate:"required"`
+	URLs []string `key:"urls" validate:"required,dive,url"`
+	// Subject is required in the JetStream and core NATS modes only,
+	// unless the SubjectTemplate is set.
+	Subject string `key:"subject"`
+	// SubjectTemplate is a template of subjects the destination renders for each record,
+	// it's used instead of the Subject.
+	SubjectTemplate string `key:"subjectTemplate"`
+	// Mode defines a communication model the connector exchanges messages with.
+	Mode Mode `key:"mode" validate:"oneof=0 1 2 3"`
+	// KVBucket is a name of a Key-Value bucket used in the Key-Value mode.
+	KVBucket string `key:"kv.bucket" validate:"required_if=Mode 1"`
+	// ObjectBucket is a name of an Object Store bucket used in the Object Store mode.
+	ObjectBucket string `key:"object.bucket" validate:"required_if=Mode 2"`
 	// ConnectionName might come in handy when it comes to monitoring and so.
 	// See https://docs.nats.io/using-nats/developer/connecting/name.
 	ConnectionName string `key:"connectionName"`
@@ -81,6 +115,8 @@ type Config struct {
 	// ReconnectWait sets the time to backoff after attempting a reconnect
 	// to a server that we were already connected to previously.
 	ReconnectWait time.Duration `key:"reconnectWait"`
+	// Stream is a stream the connector provisions in the JetStream mode, if its name is set.
+	Stream StreamConfig
 }
 
 // Parse maps the incoming map to the Config and validates it.
@@ -88,6 +124,9 @@ func Parse(cfg map[string]string) (Config, error) {
 	config := Config{
 		URLs:                    strings.Split(cfg[KeyURLs], ","),
 		Subject:                 cfg[KeySubject],
+		SubjectTemplate:         cfg[KeySubjectTemplate],
+		KVBucket:                cfg[KeyKVBucket],
+		ObjectBucket:            cfg[KeyObjectBucket],
 		ConnectionName:          generateConnectionName(),
 		NKeyPath:                cfg[KeyNKeyPath],
 		CredentialsFilePath:     cfg[KeyCredentialsFilePath],
@@ -102,6 +141,10 @@ func Parse(cfg map[string]string) (Config, error) {
 		config.ConnectionName = connectionName
 	}
 
+	if err := config.parseMode(cfg[KeyMode]); err != nil {
+		return Config{}, fmt.Errorf("parse mode: %w", err)
+	}
+
 	if err := config.parseMaxReconnects(cfg[KeyMaxReconnects]); err != nil {
 		return Config{}, fmt.Errorf("parse max reconnects: %w", err)
 	}
@@ -110,7 +153,14 @@ func Parse(cfg map[string]string) (Config, error) {
 		return Config{}, fmt.Errorf("parse reconnect wait: %w", err)
 	}
 
-	if err := validator.Validate(&config); err != nil {
+	if err := config.parseStream(cfg); err != nil {
+		return Config{}, fmt.Errorf("parse stream: %w", err)
+	}
+
+	// the subject depends on the subject template, and the stream fields depend on the stream name,
+	// which cannot be expressed with validate tags
+	err := multierr.Combine(validator.Validate(&config), config.validateSubject(), config.validateStream())
+	if err != nil {
 		return Config{}, fmt.Errorf("validate config: %w", err)
 	}
 
@@ -122,6 +172,38 @@ func (c *Config) ToURL() string {
 	return strings.Join(c.URLs, ",")
 }
 
+// validateSubject checks that either the subject or the subject template is set
+// in the modes which exchange messages with subjects.
+func (c *Config) validateSubject() error {
+	if c.Mode != ModeJetStream && c.Mode != ModeCore {
+		return nil
+	}
+
+	if c.Subject == "" && c.SubjectTemplate == "" {
+		return fmt.Errorf("%q value must be set", KeySubject)
+	}
+
+	return nil
+}
+
+// parseMode parses and converts the mode string into Mode.
+func (c *Config) parseMode(modeStr string) error {
+	switch strings.ToLower(modeStr) {
+	case "jetstream", "":
+		c.Mode = ModeJetStream
+	case "kv":
+		c.Mode = ModeKV
+	case "object":
+		c.Mode = ModeObject
+	case "core":
+		c.Mode = ModeCore
+	default:
+		return fmt.Errorf("invalid mode %q", modeStr)
+	}
+
+	return nil
+}
+
 // parseMaxReconnects parses the maxReconnects string and
 // if it's not empty set cfg.MaxReconnects to its integer representation.
 func (c *Config) parseMaxReconnects(maxReconnectsStr string) error {
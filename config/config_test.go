@@ -18,6 +18,9 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
 )
 
 func TestParse(t *testing.T) {
@@ -107,6 +110,105 @@ func TestParse(t *testing.T) {
 			want:    Config{},
 			wantErr: true,
 		},
+		{
+			name: "success, kv mode without subject",
+			args: args{
+				cfg: map[string]string{
+					KeyURLs:     "nats://127.0.0.1:1222",
+					KeyMode:     "kv",
+					KeyKVBucket: "settings",
+				},
+			},
+			want: Config{
+				URLs:          []string{"nats://127.0.0.1:1222"},
+				Mode:          ModeKV,
+				KVBucket:      "settings",
+				MaxReconnects: DefaultMaxReconnects,
+				ReconnectWait: DefaultReconnectWait,
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail, required field (kv.bucket) is missing in kv mode",
+			args: args{
+				cfg: map[string]string{
+					KeyURLs: "nats://127.0.0.1:1222",
+					KeyMode: "kv",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "success, object mode without subject",
+			args: args{
+				cfg: map[string]string{
+					KeyURLs:         "nats://127.0.0.1:1222",
+					KeyMode:         "object",
+					KeyObjectBucket: "files",
+				},
+			},
+			want: Config{
+				URLs:          []string{"nats://127.0.0.1:1222"},
+				Mode:          ModeObject,
+				ObjectBucket:  "files",
+				MaxReconnects: DefaultMaxReconnects,
+				ReconnectWait: DefaultReconnectWait,
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail, required field (object.bucket) is missing in object mode",
+			args: args{
+				cfg: map[string]string{
+					KeyURLs: "nats://127.0.0.1:1222",
+					KeyMode: "object",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "success, core mode",
+			args: args{
+				cfg: map[string]string{
+					KeyURLs:    "nats://127.0.0.1:1222",
+					KeySubject: "telemetry.>",
+					KeyMode:    "core",
+				},
+			},
+			want: Config{
+				URLs:          []string{"nats://127.0.0.1:1222"},
+				Subject:       "telemetry.>",
+				Mode:          ModeCore,
+				MaxReconnects: DefaultMaxReconnects,
+				ReconnectWait: DefaultReconnectWait,
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail, required field (subject) is missing in core mode",
+			args: args{
+				cfg: map[string]string{
+					KeyURLs: "nats://127.0.0.1:1222",
+					KeyMode: "core",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, invalid mode",
+			args: args{
+				cfg: map[string]string{
+					KeyURLs:    "nats://127.0.0.1:1222",
+					KeySubject: "foo",
+					KeyMode:    "stream",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
 		{
 			name: "fail, invalid url",
 			args: args{
@@ -184,6 +286,132 @@ func TestParse(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "success, stream with defaults",
+			args: args{
+				cfg: map[string]string{
+					KeyURLs:       "nats://127.0.0.1:1222",
+					KeySubject:    "orders.>",
+					KeyStreamName: "orders",
+				},
+			},
+			want: Config{
+				URLs:          []string{"nats://127.0.0.1:1222"},
+				Subject:       "orders.>",
+				MaxReconnects: DefaultMaxReconnects,
+				ReconnectWait: DefaultReconnectWait,
+				Stream: StreamConfig{
+					Name:      "orders",
+					Subjects:  []string{"orders.>"},
+					Retention: nats.LimitsPolicy,
+					Storage:   nats.FileStorage,
+					Replicas:  DefaultStreamReplicas,
+					MaxBytes:  DefaultStreamMaxBytes,
+					Discard:   nats.DiscardOld,
+					set:       map[string]bool{},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, stream",
+			args: args{
+				cfg: map[string]string{
+					KeyURLs:                  "nats://127.0.0.1:1222",
+					KeySubject:               "orders.eu",
+					KeyStreamName:            "orders",
+					KeyStreamSubjects:        "orders.eu, orders.us",
+					KeyStreamRetention:       "workqueue",
+					KeyStreamStorage:         "memory",
+					KeyStreamReplicas:        "3",
+					KeyStreamMaxAge:          "24h",
+					KeyStreamMaxBytes:        "1048576",
+					KeyStreamDuplicateWindow: "5m",
+					KeyStreamDiscard:         "new",
+					KeyStreamUpdate:          "true",
+				},
+			},
+			want: Config{
+				URLs:          []string{"nats://127.0.0.1:1222"},
+				Subject:       "orders.eu",
+				MaxReconnects: DefaultMaxReconnects,
+				ReconnectWait: DefaultReconnectWait,
+				Stream: StreamConfig{
+					Name:            "orders",
+					Subjects:        []string{"orders.eu", "orders.us"},
+					Retention:       nats.WorkQueuePolicy,
+					Storage:         nats.MemoryStorage,
+					Replicas:        3,
+					MaxAge:          24 * time.Hour,
+					MaxBytes:        1048576,
+					DuplicateWindow: 5 * time.Minute,
+					Discard:         nats.DiscardNew,
+					Update:          true,
+					set: map[string]bool{
+						KeyStreamSubjects:        true,
+						KeyStreamRetention:       true,
+						KeyStreamStorage:         true,
+						KeyStreamReplicas:        true,
+						KeyStreamMaxAge:          true,
+						KeyStreamMaxBytes:        true,
+						KeyStreamDuplicateWindow: true,
+						KeyStreamDiscard:         true,
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail, invalid stream retention",
+			args: args{
+				cfg: map[string]string{
+					KeyURLs:            "nats://127.0.0.1:1222",
+					KeySubject:         "orders",
+					KeyStreamName:      "orders",
+					KeyStreamRetention: "forever",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, stream in kv mode",
+			args: args{
+				cfg: map[string]string{
+					KeyURLs:       "nats://127.0.0.1:1222",
+					KeyMode:       "kv",
+					KeyKVBucket:   "settings",
+					KeyStreamName: "settings",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, stream without subjects",
+			args: args{
+				cfg: map[string]string{
+					KeyURLs:            "nats://127.0.0.1:1222",
+					KeySubjectTemplate: "orders.{{.Operation}}",
+					KeyStreamName:      "orders",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, zero stream replicas",
+			args: args{
+				cfg: map[string]string{
+					KeyURLs:           "nats://127.0.0.1:1222",
+					KeySubject:        "orders",
+					KeyStreamName:     "orders",
+					KeyStreamReplicas: "0",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
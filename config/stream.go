@@ -0,0 +1,261 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/multierr"
+)
+
+const (
+	// DefaultStreamReplicas is the default number of replicas of a provisioned stream.
+	DefaultStreamReplicas = 1
+	// DefaultStreamMaxBytes is the default max size of a provisioned stream, -1 means unlimited.
+	DefaultStreamMaxBytes = -1
+)
+
+const (
+	// KeyStreamName is a config name for a name of a provisioned stream.
+	KeyStreamName = "stream.name"
+	// KeyStreamSubjects is a config name for a list of subjects of a provisioned stream.
+	KeyStreamSubjects = "stream.subjects"
+	// KeyStreamRetention is a config name for a retention policy of a provisioned stream.
+	KeyStreamRetention = "stream.retention"
+	// KeyStreamStorage is a config name for a storage type of a provisioned stream.
+	KeyStreamStorage = "stream.storage"
+	// KeyStreamReplicas is a config name for a number of replicas of a provisioned stream.
+	KeyStreamReplicas = "stream.replicas"
+	// KeyStreamMaxAge is a config name for a max age of messages of a provisioned stream.
+	KeyStreamMaxAge = "stream.maxAge"
+	// KeyStreamMaxBytes is a config name for a max size of a provisioned stream.
+	KeyStreamMaxBytes = "stream.maxBytes"
+	// KeyStreamDuplicateWindow is a config name for a duplicate window of a provisioned stream.
+	KeyStreamDuplicateWindow = "stream.duplicateWindow"
+	// KeyStreamDiscard is a config name for a discard policy of a provisioned stream.
+	KeyStreamDiscard = "stream.discard"
+	// KeyStreamUpdate is a config name for a flag which allows updating an existing stream.
+	KeyStreamUpdate = "stream.update"
+)
+
+// StreamConfig holds the configuration of a stream the connector provisions in the JetStream mode.
+// The stream is provisioned only if the Name is set.
+type StreamConfig struct {
+	Name string `key:"stream.name"`
+	// Subjects is a list of subjects the stream covers, it defaults to the Subject.
+	Subjects  []string             `key:"stream.subjects"`
+	Retention nats.RetentionPolicy `key:"stream.retention"`
+	Storage   nats.StorageType     `key:"stream.storage"`
+	Replicas  int                  `key:"stream.replicas"`
+	// MaxAge is the max age of messages in the stream, zero means unlimited.
+	MaxAge time.Duration `key:"stream.maxAge"`
+	// MaxBytes is the max size of the stream in bytes, -1 means unlimited.
+	MaxBytes int64 `key:"stream.maxBytes"`
+	// DuplicateWindow is the window messages are deduplicated within, zero means the server default.
+	DuplicateWindow time.Duration      `key:"stream.duplicateWindow"`
+	Discard         nats.DiscardPolicy `key:"stream.discard"`
+	// Update makes the connector update an existing stream which differs from the config,
+	// otherwise the connector fails.
+	Update bool `key:"stream.update"`
+	// set holds the keys of the fields which were set explicitly,
+	// only they are compared with and applied to an existing stream.
+	set map[string]bool
+}
+
+// streamFieldKeys are the keys of the stream fields which are compared with an existing stream.
+var streamFieldKeys = []string{
+	KeyStreamSubjects, KeyStreamRetention, KeyStreamStorage, KeyStreamReplicas,
+	KeyStreamMaxAge, KeyStreamMaxBytes, KeyStreamDuplicateWindow, KeyStreamDiscard,
+}
+
+// IsSet reports whether the field with the given key was set explicitly.
+func (s StreamConfig) IsSet(key string) bool {
+	return s.set[key]
+}
+
+// parseStream parses the stream.* fields and sets default values for empty fields.
+// The default values are used for a new stream only, as the fields which were not set explicitly
+// are kept as they are in an existing stream.
+// The fields are left empty if the stream name is not set, as the stream is not provisioned then.
+func (c *Config) parseStream(cfg map[string]string) error {
+	if cfg[KeyStreamName] == "" {
+		return nil
+	}
+
+	c.Stream = StreamConfig{
+		Name:     cfg[KeyStreamName],
+		Replicas: DefaultStreamReplicas,
+		MaxBytes: DefaultStreamMaxBytes,
+		set:      make(map[string]bool),
+	}
+
+	for _, key := range streamFieldKeys {
+		if cfg[key] != "" {
+			c.Stream.set[key] = true
+		}
+	}
+
+	if cfg[KeyStreamSubjects] != "" {
+		for _, subject := range strings.Split(cfg[KeyStreamSubjects], ",") {
+			c.Stream.Subjects = append(c.Stream.Subjects, strings.TrimSpace(subject))
+		}
+	}
+
+	if err := c.Stream.parsePolicies(cfg); err != nil {
+		return err
+	}
+
+	if err := c.Stream.parseLimits(cfg); err != nil {
+		return err
+	}
+
+	if cfg[KeyStreamUpdate] != "" {
+		update, err := strconv.ParseBool(cfg[KeyStreamUpdate])
+		if err != nil {
+			return fmt.Errorf("\"%s\" must be a boolean", KeyStreamUpdate)
+		}
+
+		c.Stream.Update = update
+	}
+
+	// the stream covers the subject the connector exchanges messages with by default
+	if len(c.Stream.Subjects) == 0 && c.Subject != "" {
+		c.Stream.Subjects = []string{c.Subject}
+	}
+
+	return nil
+}
+
+// parsePolicies parses the retention, storage and discard policies of the stream.
+func (s *StreamConfig) parsePolicies(cfg map[string]string) error {
+	switch strings.ToLower(cfg[KeyStreamRetention]) {
+	case "limits", "":
+		s.Retention = nats.LimitsPolicy
+	case "interest":
+		s.Retention = nats.InterestPolicy
+	case "workqueue":
+		s.Retention = nats.WorkQueuePolicy
+	default:
+		return fmt.Errorf("invalid %q value %q", KeyStreamRetention, cfg[KeyStreamRetention])
+	}
+
+	switch strings.ToLower(cfg[KeyStreamStorage]) {
+	case "file", "":
+		s.Storage = nats.FileStorage
+	case "memory":
+		s.Storage = nats.MemoryStorage
+	default:
+		return fmt.Errorf("invalid %q value %q", KeyStreamStorage, cfg[KeyStreamStorage])
+	}
+
+	switch strings.ToLower(cfg[KeyStreamDiscard]) {
+	case "old", "":
+		s.Discard = nats.DiscardOld
+	case "new":
+		s.Discard = nats.DiscardNew
+	default:
+		return fmt.Errorf("invalid %q value %q", KeyStreamDiscard, cfg[KeyStreamDiscard])
+	}
+
+	return nil
+}
+
+// parseLimits parses the replicas, the max age and size, and the duplicate window of the stream.
+func (s *StreamConfig) parseLimits(cfg map[string]string) error {
+	if cfg[KeyStreamReplicas] != "" {
+		replicas, err := strconv.Atoi(cfg[KeyStreamReplicas])
+		if err != nil {
+			return fmt.Errorf("\"%s\" must be an integer", KeyStreamReplicas)
+		}
+
+		s.Replicas = replicas
+	}
+
+	if cfg[KeyStreamMaxAge] != "" {
+		maxAge, err := time.ParseDuration(cfg[KeyStreamMaxAge])
+		if err != nil {
+			return fmt.Errorf("\"%s\" must be a valid duration", KeyStreamMaxAge)
+		}
+
+		s.MaxAge = maxAge
+	}
+
+	if cfg[KeyStreamMaxBytes] != "" {
+		maxBytes, err := strconv.ParseInt(cfg[KeyStreamMaxBytes], 10, 64)
+		if err != nil {
+			return fmt.Errorf("\"%s\" must be an integer", KeyStreamMaxBytes)
+		}
+
+		s.MaxBytes = maxBytes
+	}
+
+	if cfg[KeyStreamDuplicateWindow] != "" {
+		duplicateWindow, err := time.ParseDuration(cfg[KeyStreamDuplicateWindow])
+		if err != nil {
+			return fmt.Errorf("\"%s\" must be a valid duration", KeyStreamDuplicateWindow)
+		}
+
+		s.DuplicateWindow = duplicateWindow
+	}
+
+	return nil
+}
+
+// validateStream checks the stream config if the stream is provisioned.
+func (c *Config) validateStream() error {
+	if c.Stream.Name == "" {
+		return nil
+	}
+
+	var err error
+
+	if c.Mode != ModeJetStream {
+		err = multierr.Append(err, fmt.Errorf("%q can be set in the jetstream mode only", KeyStreamName))
+	}
+
+	if len(c.Stream.Subjects) == 0 {
+		err = multierr.Append(err, fmt.Errorf("%q value must be set if %q is not set", KeyStreamSubjects, KeySubject))
+	}
+
+	if c.Stream.Replicas < 1 {
+		err = multierr.Append(err, fmt.Errorf("%q value must be greater than or equal to 1", KeyStreamReplicas))
+	}
+
+	if c.Stream.MaxAge < 0 || c.Stream.DuplicateWindow < 0 {
+		err = multierr.Append(err, fmt.Errorf("%q and %q values must not be negative",
+			KeyStreamMaxAge, KeyStreamDuplicateWindow))
+	}
+
+	return err
+}
+
+// ToNATS returns a NATS stream config the stream is provisioned with.
+func (s StreamConfig) ToNATS() *nats.StreamConfig {
+	return &nats.StreamConfig{
+		Name:       s.Name,
+		Subjects:   s.Subjects,
+		Retention:  s.Retention,
+		Storage:    s.Storage,
+		Replicas:   s.Replicas,
+		MaxAge:     s.MaxAge,
+		MaxBytes:   s.MaxBytes,
+		Duplicates: s.DuplicateWindow,
+		Discard:    s.Discard,
+	}
+}
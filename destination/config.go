@@ -15,11 +15,16 @@
 package destination
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/conduitio-labs/conduit-connector-nats-jetstream/config"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/kv"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/message"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/subject"
 	"github.com/conduitio-labs/conduit-connector-nats-jetstream/validator"
 )
 
@@ -28,6 +33,8 @@ const (
 	defaultRetryWait = time.Second * 5
 	// defaultRetryAttempts is the retry number of attempts when ErrNoResponders is encountered.
 	defaultRetryAttempts = 3
+	// defaultMaxPending is the default maximum number of published messages waiting for acknowledgements.
+	defaultMaxPending = 4000
 )
 
 const (
@@ -35,6 +42,22 @@ const (
 	ConfigKeyRetryWait = "retryWait"
 	// ConfigKeyRetryAttempts is a config name for a retry attempts count.
 	ConfigKeyRetryAttempts = "retryAttempts"
+	// ConfigKeyMaxPending is a config name for a max number of messages waiting for acknowledgements.
+	ConfigKeyMaxPending = "maxPending"
+	// ConfigKeyHeadersInclude is a config name for a list of patterns of headers added to messages.
+	ConfigKeyHeadersInclude = "headers.include"
+	// ConfigKeyHeadersExclude is a config name for a list of patterns of headers not added to messages.
+	ConfigKeyHeadersExclude = "headers.exclude"
+	// ConfigKeyHeadersPrefix is a config name for a prefix of header names.
+	ConfigKeyHeadersPrefix = "headers.prefix"
+	// ConfigKeyPayloadFormat is a config name for a format of message payloads.
+	ConfigKeyPayloadFormat = "payloadFormat"
+	// ConfigKeyMsgIDSource is a config name for a message ID source.
+	ConfigKeyMsgIDSource = "msgIdSource"
+	// ConfigKeyKVDeleteOperation is a config name for an operation delete records are applied with.
+	ConfigKeyKVDeleteOperation = "kv.deleteOperation"
+	// ConfigKeyKVOptimisticConcurrency is a config name for a Key-Value optimistic concurrency flag.
+	ConfigKeyKVOptimisticConcurrency = "kv.optimisticConcurrency"
 )
 
 // Config holds destination specific configurable values.
@@ -43,6 +66,24 @@ type Config struct {
 
 	RetryWait     time.Duration `key:"retryWait"`
 	RetryAttempts int           `key:"retryAttempts"`
+	// MaxPending is the maximum number of published messages waiting for acknowledgements in the JetStream mode.
+	MaxPending int `key:"maxPending" validate:"min=1"`
+	// HeadersInclude is a list of patterns of headers added to messages, all headers are added if it's empty.
+	HeadersInclude []string `key:"headers.include"`
+	// HeadersExclude is a list of patterns of headers which are not added to messages.
+	HeadersExclude []string `key:"headers.exclude"`
+	// HeadersPrefix is prepended to names of headers added to messages.
+	HeadersPrefix string `key:"headers.prefix"`
+	// PayloadFormat defines which parts of a record make up a message payload in the JetStream and Core modes.
+	PayloadFormat message.PayloadFormat `key:"payloadFormat" validate:"oneof=0 1 2 3 4"`
+	// MsgIDSource defines where the connector takes message IDs from, which JetStream uses to discard duplicates.
+	// It's used in the JetStream mode only.
+	MsgIDSource message.MsgIDSource `key:"msgIdSource"`
+	// KVDeleteOperation defines whether delete records delete or purge keys in the Key-Value mode.
+	KVDeleteOperation kv.DeleteOperation `key:"kv.deleteOperation" validate:"oneof=0 1"`
+	// KVOptimisticConcurrency makes the connector fail if a key was modified by someone else
+	// since the connector saw it last time.
+	KVOptimisticConcurrency bool `key:"kv.optimisticConcurrency"`
 }
 
 // Parse maps the incoming map to the Config and validates it.
@@ -53,7 +94,8 @@ func Parse(cfg map[string]string) (Config, error) {
 	}
 
 	destinationConfig := Config{
-		Config: common,
+		Config:        common,
+		HeadersPrefix: cfg[ConfigKeyHeadersPrefix],
 	}
 
 	if err := destinationConfig.parseFields(cfg); err != nil {
@@ -64,6 +106,16 @@ func Parse(cfg map[string]string) (Config, error) {
 		return Config{}, fmt.Errorf("validate destination config: %w", err)
 	}
 
+	if err := destinationConfig.validateMsgIDSource(); err != nil {
+		return Config{}, fmt.Errorf("validate message id source: %w", err)
+	}
+
+	if destinationConfig.SubjectTemplate != "" {
+		if _, err := subject.NewTemplate(destinationConfig.SubjectTemplate); err != nil {
+			return Config{}, fmt.Errorf("parse %q: %w", config.KeySubjectTemplate, err)
+		}
+	}
+
 	return destinationConfig, nil
 }
 
@@ -89,5 +141,145 @@ func (c *Config) parseFields(cfg map[string]string) error {
 		c.RetryAttempts = retryAttempts
 	}
 
+	c.MaxPending = defaultMaxPending
+	if cfg[ConfigKeyMaxPending] != "" {
+		maxPending, err := strconv.Atoi(cfg[ConfigKeyMaxPending])
+		if err != nil {
+			return fmt.Errorf("parse %q: %w", ConfigKeyMaxPending, err)
+		}
+
+		c.MaxPending = maxPending
+	}
+
+	headersInclude, err := parseHeaderPatterns(cfg[ConfigKeyHeadersInclude])
+	if err != nil {
+		return fmt.Errorf("parse %q: %w", ConfigKeyHeadersInclude, err)
+	}
+
+	c.HeadersInclude = headersInclude
+
+	headersExclude, err := parseHeaderPatterns(cfg[ConfigKeyHeadersExclude])
+	if err != nil {
+		return fmt.Errorf("parse %q: %w", ConfigKeyHeadersExclude, err)
+	}
+
+	c.HeadersExclude = headersExclude
+
+	switch strings.ToLower(cfg[ConfigKeyPayloadFormat]) {
+	case "after", "":
+		c.PayloadFormat = message.PayloadFormatAfter
+	case "before":
+		c.PayloadFormat = message.PayloadFormatBefore
+	case "both":
+		c.PayloadFormat = message.PayloadFormatBoth
+	case "key-only-on-delete":
+		c.PayloadFormat = message.PayloadFormatKeyOnlyOnDelete
+	case "opencdc":
+		c.PayloadFormat = message.PayloadFormatOpenCDC
+	default:
+		return fmt.Errorf("parse %q: invalid payload format %q", ConfigKeyPayloadFormat, cfg[ConfigKeyPayloadFormat])
+	}
+
+	if err := c.parseMsgIDSource(cfg[ConfigKeyMsgIDSource]); err != nil {
+		return fmt.Errorf("parse %q: %w", ConfigKeyMsgIDSource, err)
+	}
+
+	switch strings.ToLower(cfg[ConfigKeyKVDeleteOperation]) {
+	case "delete", "":
+		c.KVDeleteOperation = kv.DeleteOperationDelete
+	case "purge":
+		c.KVDeleteOperation = kv.DeleteOperationPurge
+	default:
+		return fmt.Errorf("parse %q: invalid delete operation %q",
+			ConfigKeyKVDeleteOperation, cfg[ConfigKeyKVDeleteOperation])
+	}
+
+	if cfg[ConfigKeyKVOptimisticConcurrency] != "" {
+		optimisticConcurrency, err := strconv.ParseBool(cfg[ConfigKeyKVOptimisticConcurrency])
+		if err != nil {
+			return fmt.Errorf("parse %q: %w", ConfigKeyKVOptimisticConcurrency, err)
+		}
+
+		c.KVOptimisticConcurrency = optimisticConcurrency
+	}
+
+	return nil
+}
+
+// validateMsgIDSource checks that the message ID source is set in the JetStream mode only,
+// as core NATS subjects don't discard duplicates.
+func (c *Config) validateMsgIDSource() error {
+	if c.MsgIDSource.Type != message.MsgIDSourceNone && c.Mode != config.ModeJetStream {
+		return fmt.Errorf("%q can be set in the jetstream mode only", ConfigKeyMsgIDSource)
+	}
+
+	return nil
+}
+
+// parseHeaderPatterns splits a comma-separated list of patterns of header names and validates them.
+func parseHeaderPatterns(patternsStr string) ([]string, error) {
+	if patternsStr == "" {
+		return nil, nil
+	}
+
+	patterns := strings.Split(patternsStr, ",")
+	for i := range patterns {
+		patterns[i] = strings.TrimSpace(patterns[i])
+
+		if err := message.ValidatePattern(patterns[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return patterns, nil
+}
+
+// parseMsgIDSource parses the msgIdSource string and converts it into message.MsgIDSource.
+// The string is either empty, which means message IDs are not set, or one of:
+//   - key - the record key;
+//   - position - a SHA-256 hash of the record position;
+//   - metadata:<field> - a value of the record metadata field;
+//   - template:<template> - a Go text/template rendered for the record.
+func (c *Config) parseMsgIDSource(msgIDSourceStr string) error {
+	msgIDSourceType, value, _ := strings.Cut(msgIDSourceStr, ":")
+
+	switch strings.ToLower(msgIDSourceType) {
+	case "":
+		c.MsgIDSource = message.MsgIDSource{Type: message.MsgIDSourceNone}
+
+	case "key":
+		c.MsgIDSource = message.MsgIDSource{Type: message.MsgIDSourceKey}
+
+	case "position":
+		c.MsgIDSource = message.MsgIDSource{Type: message.MsgIDSourcePosition}
+
+	case "metadata":
+		if value == "" {
+			return errors.New("metadata field must be set in the format metadata:<field>")
+		}
+
+		c.MsgIDSource = message.MsgIDSource{
+			Type:          message.MsgIDSourceMetadata,
+			MetadataField: value,
+		}
+
+	case "template":
+		if value == "" {
+			return errors.New("template must be set in the format template:<template>")
+		}
+
+		if _, err := subject.NewTemplate(value); err != nil {
+			return fmt.Errorf("parse template: %w", err)
+		}
+
+		c.MsgIDSource = message.MsgIDSource{
+			Type:     message.MsgIDSourceTemplate,
+			Template: value,
+		}
+
+	default:
+		return fmt.Errorf("invalid message id source type %q", msgIDSourceType)
+	}
+
 	return nil
 }
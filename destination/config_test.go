@@ -20,6 +20,8 @@ import (
 	"time"
 
 	"github.com/conduitio-labs/conduit-connector-nats-jetstream/config"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/kv"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/message"
 )
 
 func TestParse(t *testing.T) {
@@ -51,6 +53,7 @@ func TestParse(t *testing.T) {
 				},
 				RetryWait:     defaultRetryWait,
 				RetryAttempts: defaultRetryAttempts,
+				MaxPending:    defaultMaxPending,
 			},
 			wantErr: false,
 		},
@@ -72,6 +75,7 @@ func TestParse(t *testing.T) {
 				},
 				RetryWait:     time.Second * 3,
 				RetryAttempts: defaultRetryAttempts,
+				MaxPending:    defaultMaxPending,
 			},
 			wantErr: false,
 		},
@@ -93,6 +97,7 @@ func TestParse(t *testing.T) {
 				},
 				RetryWait:     defaultRetryWait,
 				RetryAttempts: 5,
+				MaxPending:    defaultMaxPending,
 			},
 			wantErr: false,
 		},
@@ -120,6 +125,288 @@ func TestParse(t *testing.T) {
 			want:    Config{},
 			wantErr: true,
 		},
+		{
+			name: "success, custom max pending",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:      "nats://localhost:4222",
+					config.KeySubject:   "foo",
+					ConfigKeyMaxPending: "256",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://localhost:4222"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				RetryWait:     defaultRetryWait,
+				RetryAttempts: defaultRetryAttempts,
+				MaxPending:    256,
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail, max pending is less than 1",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:      "nats://localhost:4222",
+					config.KeySubject:   "foo",
+					ConfigKeyMaxPending: "0",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "success, subject template instead of subject",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:            "nats://localhost:4222",
+					config.KeySubjectTemplate: "orders.{{.Metadata.region}}.{{.Operation}}",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:            []string{"nats://localhost:4222"},
+					SubjectTemplate: "orders.{{.Metadata.region}}.{{.Operation}}",
+					MaxReconnects:   config.DefaultMaxReconnects,
+					ReconnectWait:   config.DefaultReconnectWait,
+				},
+				RetryWait:     defaultRetryWait,
+				RetryAttempts: defaultRetryAttempts,
+				MaxPending:    defaultMaxPending,
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail, invalid subject template",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:            "nats://localhost:4222",
+					config.KeySubjectTemplate: "orders.{{.Operation",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "success, headers",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:          "nats://localhost:4222",
+					config.KeySubject:       "foo",
+					ConfigKeyHeadersInclude: "opencdc.*, table",
+					ConfigKeyHeadersExclude: "opencdc.readAt",
+					ConfigKeyHeadersPrefix:  "x-",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://localhost:4222"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				RetryWait:      defaultRetryWait,
+				RetryAttempts:  defaultRetryAttempts,
+				MaxPending:     defaultMaxPending,
+				HeadersInclude: []string{"opencdc.*", "table"},
+				HeadersExclude: []string{"opencdc.readAt"},
+				HeadersPrefix:  "x-",
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail, invalid headers pattern",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:          "nats://localhost:4222",
+					config.KeySubject:       "foo",
+					ConfigKeyHeadersExclude: "opencdc.[",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "success, key only on delete payload format",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:         "nats://localhost:4222",
+					config.KeySubject:      "foo",
+					ConfigKeyPayloadFormat: "key-only-on-delete",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://localhost:4222"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				RetryWait:     defaultRetryWait,
+				RetryAttempts: defaultRetryAttempts,
+				MaxPending:    defaultMaxPending,
+				PayloadFormat: message.PayloadFormatKeyOnlyOnDelete,
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail, invalid payload format",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:         "nats://localhost:4222",
+					config.KeySubject:      "foo",
+					ConfigKeyPayloadFormat: "avro",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "success, metadata message id source",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:       "nats://localhost:4222",
+					config.KeySubject:    "foo",
+					ConfigKeyMsgIDSource: "metadata:event.id",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://localhost:4222"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				RetryWait:     defaultRetryWait,
+				RetryAttempts: defaultRetryAttempts,
+				MaxPending:    defaultMaxPending,
+				MsgIDSource: message.MsgIDSource{
+					Type:          message.MsgIDSourceMetadata,
+					MetadataField: "event.id",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, template message id source",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:       "nats://localhost:4222",
+					config.KeySubject:    "foo",
+					ConfigKeyMsgIDSource: "template:{{.Metadata.table}}:{{.Key}}",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://localhost:4222"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				RetryWait:     defaultRetryWait,
+				RetryAttempts: defaultRetryAttempts,
+				MaxPending:    defaultMaxPending,
+				MsgIDSource: message.MsgIDSource{
+					Type:     message.MsgIDSourceTemplate,
+					Template: "{{.Metadata.table}}:{{.Key}}",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail, metadata message id source without a field",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:       "nats://localhost:4222",
+					config.KeySubject:    "foo",
+					ConfigKeyMsgIDSource: "metadata",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, invalid message id source type",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:       "nats://localhost:4222",
+					config.KeySubject:    "foo",
+					ConfigKeyMsgIDSource: "uuid",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, message id source in core mode",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:       "nats://localhost:4222",
+					config.KeyMode:       "core",
+					config.KeySubject:    "foo",
+					ConfigKeyMsgIDSource: "key",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "success, kv mode, purge with optimistic concurrency",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:                   "nats://localhost:4222",
+					config.KeyMode:                   "kv",
+					config.KeyKVBucket:               "settings",
+					ConfigKeyKVDeleteOperation:       "purge",
+					ConfigKeyKVOptimisticConcurrency: "true",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://localhost:4222"},
+					Mode:          config.ModeKV,
+					KVBucket:      "settings",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				RetryWait:               defaultRetryWait,
+				RetryAttempts:           defaultRetryAttempts,
+				MaxPending:              defaultMaxPending,
+				KVDeleteOperation:       kv.DeleteOperationPurge,
+				KVOptimisticConcurrency: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail, invalid kv delete operation",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:             "nats://localhost:4222",
+					config.KeyMode:             "kv",
+					config.KeyKVBucket:         "settings",
+					ConfigKeyKVDeleteOperation: "remove",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, invalid kv optimistic concurrency",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:                   "nats://localhost:4222",
+					config.KeyMode:                   "kv",
+					config.KeyKVBucket:               "settings",
+					ConfigKeyKVOptimisticConcurrency: "sometimes",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		tt := tt
@@ -0,0 +1,114 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/message"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/nats-io/nats.go"
+)
+
+// flushTimeout is the maximum amount of time Write waits for the server
+// if the context doesn't have its own deadline.
+const flushTimeout = time.Second * 10
+
+// Writer implements a core NATS writer.
+// It publishes messages to a plain subject without JetStream,
+// so no stream has to cover the subject, and the messages are delivered at most once.
+type Writer struct {
+	conn    *nats.Conn
+	builder *message.Builder
+}
+
+// WriterParams is an incoming params for the NewWriter function.
+type WriterParams struct {
+	Conn *nats.Conn
+	// Builder builds messages from records.
+	Builder *message.Builder
+}
+
+// NewWriter creates new instance of the Writer.
+func NewWriter(params WriterParams) (*Writer, error) {
+	return &Writer{
+		conn:    params.Conn,
+		builder: params.Builder,
+	}, nil
+}
+
+// Write publishes records into the outgoing buffer of the connection,
+// and then flushes the buffer and waits until the server processes them.
+// If a record fails, the records before it are still flushed.
+// None of the records are counted as written if the flush fails, since it's unknown which of them were sent.
+func (w *Writer) Write(ctx context.Context, records []sdk.Record) (int, error) {
+	for i, record := range records {
+		if err := w.publish(record); err != nil {
+			if flushErr := w.flush(ctx); flushErr != nil {
+				return 0, flushErr
+			}
+
+			return i, err
+		}
+	}
+
+	if err := w.flush(ctx); err != nil {
+		return 0, err
+	}
+
+	return len(records), nil
+}
+
+// publish puts a record into the outgoing buffer of the connection.
+func (w *Writer) publish(record sdk.Record) error {
+	msg, err := w.builder.Build(record)
+	if err != nil {
+		return fmt.Errorf("build message: %w", err)
+	}
+
+	if err := w.conn.PublishMsg(msg); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+
+	return nil
+}
+
+// flush sends the buffered records to the server and waits until it processes them.
+func (w *Writer) flush(ctx context.Context) error {
+	// the NATS client requires the context to have a deadline
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, flushTimeout)
+		defer cancel()
+	}
+
+	if err := w.conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("flush connection: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the underlying NATS connection.
+func (w *Writer) Close() error {
+	if w.conn != nil {
+		w.conn.Close()
+	}
+
+	return nil
+}
@@ -21,7 +21,12 @@ import (
 
 	common "github.com/conduitio-labs/conduit-connector-nats-jetstream/common"
 	"github.com/conduitio-labs/conduit-connector-nats-jetstream/config"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/core"
 	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/jetstream"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/kv"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/message"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/object"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/subject"
 	sdk "github.com/conduitio/conduit-connector-sdk"
 	"github.com/nats-io/nats.go"
 )
@@ -31,7 +36,16 @@ type Destination struct {
 	sdk.UnimplementedDestination
 
 	config Config
-	writer *jetstream.Writer
+	writer Writer
+}
+
+// Writer defines a writer which applies records to a communication model.
+type Writer interface {
+	// Write writes a batch of records and returns the number of records written,
+	// which are always the first records of the batch.
+	Write(ctx context.Context, records []sdk.Record) (int, error)
+	// Close closes the writer's connection.
+	Close() error
 }
 
 // NewDestination creates new instance of the Destination.
@@ -40,16 +54,41 @@ func NewDestination() sdk.Destination {
 }
 
 func (d *Destination) Parameters() map[string]sdk.Parameter {
-	return map[string]sdk.Parameter{
+	params := map[string]sdk.Parameter{
 		config.KeyURLs: {
 			Default:     "",
 			Required:    true,
 			Description: "The connection URLs pointed to NATS instances.",
 		},
 		config.KeySubject: {
+			Default:  "",
+			Required: false,
+			Description: "A name of a subject to which the connector should write, required for the jetstream and core modes " +
+				"unless the subjectTemplate is set.",
+		},
+		config.KeySubjectTemplate: {
+			Default:  "",
+			Required: false,
+			Description: "A Go text/template of a subject rendered for each record, used instead of the subject " +
+				"in the jetstream and core modes. The template can refer to the .Key, .Operation, .Metadata " +
+				"and .Payload fields, e.g. orders.{{.Metadata.region}}.{{.Operation}}.",
+		},
+		config.KeyMode: {
+			Default:  "jetstream",
+			Required: false,
+			Description: "Defines a communication model the destination writes messages with. " +
+				"Allowed values are jetstream, kv, object and core. " +
+				"The core mode publishes messages to a subject without JetStream and delivers them at most once.",
+		},
+		config.KeyKVBucket: {
 			Default:     "",
-			Required:    true,
-			Description: "A name of a subject to which the connector should write.",
+			Required:    false,
+			Description: "A name of a Key-Value bucket to write to, required for the kv mode.",
+		},
+		config.KeyObjectBucket: {
+			Default:     "",
+			Required:    false,
+			Description: "A name of an Object Store bucket to write to, required for the object mode.",
 		},
 		config.KeyConnectionName: {
 			Default:     "conduit-connection-<uuid>",
@@ -106,7 +145,64 @@ func (d *Destination) Parameters() map[string]sdk.Parameter {
 			Required:    false,
 			Description: "Sets a numbers of attempts to send a message, if send fails.",
 		},
+		ConfigKeyMaxPending: {
+			Default:  "4000",
+			Required: false,
+			Description: "The maximum number of messages published asynchronously before waiting for their " +
+				"acknowledgements, larger batches are published in several windows. Used in the jetstream mode.",
+		},
+		ConfigKeyHeadersInclude: {
+			Default:  "",
+			Required: false,
+			Description: "A comma-separated list of patterns of headers added to messages, e.g. opencdc.*, " +
+				"the headers hold the record key, operation and metadata. All headers are added if it's empty. " +
+				"Used in the jetstream and core modes.",
+		},
+		ConfigKeyHeadersExclude: {
+			Default:  "",
+			Required: false,
+			Description: "A comma-separated list of patterns of headers which are not added to messages. " +
+				"Used in the jetstream and core modes.",
+		},
+		ConfigKeyHeadersPrefix: {
+			Default:     "",
+			Required:    false,
+			Description: "A prefix of names of headers added to messages. Used in the jetstream and core modes.",
+		},
+		ConfigKeyPayloadFormat: {
+			Default:  "after",
+			Required: false,
+			Description: "Defines which parts of a record make up a message payload. Allowed values are after, before, " +
+				"both (a JSON object with the data before and after the change), key-only-on-delete (the record key " +
+				"for delete records and the data after the change for others) and opencdc (the whole record " +
+				"in the OpenCDC format). Used in the jetstream and core modes.",
+		},
+		ConfigKeyMsgIDSource: {
+			Default:  "",
+			Required: false,
+			Description: "Defines where message IDs are taken from, JetStream discards messages with the same ID " +
+				"published within the stream's duplicate window. Allowed values are key, position (a hash of " +
+				"the record position), metadata:<field> and template:<template>. Message IDs are not set if it's empty. " +
+				"Used in the jetstream mode only.",
+		},
+		ConfigKeyKVDeleteOperation: {
+			Default:     "delete",
+			Required:    false,
+			Description: "Defines whether delete records delete or purge keys in the kv mode.",
+		},
+		ConfigKeyKVOptimisticConcurrency: {
+			Default:  "false",
+			Required: false,
+			Description: "Makes the connector fail if a key was modified by someone else " +
+				"since the connector saw it last time, used in the kv mode.",
+		},
+	}
+
+	for key, param := range common.StreamParameters() {
+		params[key] = param
 	}
+
+	return params
 }
 
 // Configure parses and initializes the config.
@@ -122,39 +218,140 @@ func (d *Destination) Configure(_ context.Context, cfg map[string]string) error
 }
 
 // Open makes sure everything is prepared to receive records.
-func (d *Destination) Open(context.Context) error {
+func (d *Destination) Open(ctx context.Context) error {
 	opts, err := common.GetConnectionOptions(d.config.Config)
 	if err != nil {
 		return fmt.Errorf("get connection options: %s", err)
 	}
 
+	// publish errors are returned from the Write method, so the connection events are only logged
+	opts = append(opts, common.GetConnectionEventOptions(ctx, nil)...)
+
 	conn, err := nats.Connect(strings.Join(d.config.URLs, ","), opts...)
 	if err != nil {
 		return fmt.Errorf("connect to NATS: %w", err)
 	}
 
-	d.writer, err = jetstream.NewWriter(jetstream.WriterParams{
-		Conn:          conn,
-		Subject:       d.config.Subject,
-		RetryWait:     d.config.RetryWait,
-		RetryAttempts: d.config.RetryAttempts,
-	})
+	// the stream is provisioned only in the jetstream mode, which is validated by the config
+	if err := common.ProvisionStream(ctx, conn, d.config.Stream); err != nil {
+		conn.Close()
+
+		return fmt.Errorf("provision stream: %w", err)
+	}
+
+	d.writer, err = d.newWriter(conn)
 	if err != nil {
-		return fmt.Errorf("init jetstream writer: %w", err)
+		conn.Close()
+
+		return err
 	}
 
 	return nil
 }
 
-// Write writes a record into a Destination.
-func (d *Destination) Write(_ context.Context, records []sdk.Record) (int, error) {
-	for i, record := range records {
-		if err := d.writer.Write(record); err != nil {
-			return i, fmt.Errorf("write: %w", err)
+// newBuilder creates a builder of messages published in the jetstream and core modes.
+func (d *Destination) newBuilder() (*message.Builder, error) {
+	var subjectTemplate *subject.Template
+	if d.config.SubjectTemplate != "" {
+		var err error
+
+		subjectTemplate, err = subject.NewTemplate(d.config.SubjectTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("parse subject template: %w", err)
+		}
+	}
+
+	builder, err := message.NewBuilder(message.BuilderParams{
+		Subject:         d.config.Subject,
+		SubjectTemplate: subjectTemplate,
+		HeaderPrefix:    d.config.HeadersPrefix,
+		IncludeHeaders:  d.config.HeadersInclude,
+		ExcludeHeaders:  d.config.HeadersExclude,
+		PayloadFormat:   d.config.PayloadFormat,
+		MsgIDSource:     d.config.MsgIDSource,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("init message builder: %w", err)
+	}
+
+	return builder, nil
+}
+
+// newWriter creates a writer for the configured mode.
+func (d *Destination) newWriter(conn *nats.Conn) (Writer, error) {
+	switch d.config.Mode {
+	case config.ModeJetStream:
+		builder, err := d.newBuilder()
+		if err != nil {
+			return nil, err
+		}
+
+		writer, err := jetstream.NewWriter(jetstream.WriterParams{
+			Conn:          conn,
+			Builder:       builder,
+			RetryWait:     d.config.RetryWait,
+			RetryAttempts: d.config.RetryAttempts,
+			MaxPending:    d.config.MaxPending,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("init jetstream writer: %w", err)
+		}
+
+		return writer, nil
+
+	case config.ModeKV:
+		writer, err := kv.NewWriter(kv.WriterParams{
+			Conn:                  conn,
+			Bucket:                d.config.KVBucket,
+			DeleteOperation:       d.config.KVDeleteOperation,
+			OptimisticConcurrency: d.config.KVOptimisticConcurrency,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("init kv writer: %w", err)
+		}
+
+		return writer, nil
+
+	case config.ModeObject:
+		writer, err := object.NewWriter(object.WriterParams{
+			Conn:   conn,
+			Bucket: d.config.ObjectBucket,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("init object writer: %w", err)
+		}
+
+		return writer, nil
+
+	case config.ModeCore:
+		builder, err := d.newBuilder()
+		if err != nil {
+			return nil, err
+		}
+
+		writer, err := core.NewWriter(core.WriterParams{
+			Conn:    conn,
+			Builder: builder,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("init core writer: %w", err)
 		}
+
+		return writer, nil
+
+	default:
+		return nil, fmt.Errorf("unknown mode %d", d.config.Mode)
+	}
+}
+
+// Write writes records into a Destination.
+func (d *Destination) Write(ctx context.Context, records []sdk.Record) (int, error) {
+	written, err := d.writer.Write(ctx, records)
+	if err != nil {
+		return written, fmt.Errorf("write: %w", err)
 	}
 
-	return len(records), nil
+	return written, nil
 }
 
 // Teardown gracefully closes connections.
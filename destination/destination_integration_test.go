@@ -15,13 +15,20 @@
 package destination
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	config "github.com/conduitio-labs/conduit-connector-nats-jetstream/config"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/message"
 	test "github.com/conduitio-labs/conduit-connector-nats-jetstream/test"
 	sdk "github.com/conduitio/conduit-connector-sdk"
 	"github.com/matryer/is"
+	"github.com/nats-io/nats.go"
 )
 
 func TestDestination_Open_Success(t *testing.T) {
@@ -119,3 +126,722 @@ func TestDestination_Write(t *testing.T) {
 	err = destination.Teardown(context.Background())
 	is.NoErr(err)
 }
+
+func TestDestination_Write_KeyValue(t *testing.T) {
+	t.Parallel()
+
+	is := is.New(t)
+
+	conn, err := test.GetTestConnection()
+	is.NoErr(err)
+
+	keyValue, err := test.CreateTestKeyValue(conn, "settings_write")
+	is.NoErr(err)
+
+	destination := NewDestination()
+
+	err = destination.Configure(context.Background(), map[string]string{
+		config.KeyURLs:                   test.TestURL,
+		config.KeyMode:                   "kv",
+		config.KeyKVBucket:               "settings_write",
+		ConfigKeyKVOptimisticConcurrency: "true",
+	})
+	is.NoErr(err)
+
+	err = destination.Open(context.Background())
+	is.NoErr(err)
+
+	t.Cleanup(func() {
+		err := destination.Teardown(context.Background())
+		is.NoErr(err)
+	})
+
+	written, err := destination.Write(context.Background(), []sdk.Record{
+		{
+			Operation: sdk.OperationCreate,
+			Key:       sdk.RawData("a"),
+			Payload:   sdk.Change{After: sdk.RawData("1")},
+		},
+		{
+			Operation: sdk.OperationUpdate,
+			Key:       sdk.RawData("a"),
+			Payload:   sdk.Change{After: sdk.RawData("2")},
+		},
+		{
+			Operation: sdk.OperationCreate,
+			Key:       sdk.RawData("b"),
+			Payload:   sdk.Change{After: sdk.RawData("1")},
+		},
+		{
+			Operation: sdk.OperationDelete,
+			Key:       sdk.RawData("b"),
+		},
+		// a record without a payload puts an empty value
+		{
+			Operation: sdk.OperationCreate,
+			Key:       sdk.RawData("c"),
+		},
+	})
+	is.NoErr(err)
+	is.Equal(written, 5)
+
+	entry, err := keyValue.Get("a")
+	is.NoErr(err)
+	is.Equal(string(entry.Value()), "2")
+
+	entry, err = keyValue.Get("c")
+	is.NoErr(err)
+	is.Equal(len(entry.Value()), 0)
+
+	_, err = keyValue.Get("b")
+	is.True(errors.Is(err, nats.ErrKeyNotFound))
+
+	// the key was modified by someone else, so the update must fail
+	_, err = keyValue.Put("a", []byte("3"))
+	is.NoErr(err)
+
+	written, err = destination.Write(context.Background(), []sdk.Record{
+		{
+			Operation: sdk.OperationUpdate,
+			Key:       sdk.RawData("a"),
+			Payload:   sdk.Change{After: sdk.RawData("4")},
+		},
+	})
+	is.True(err != nil)
+	is.Equal(written, 0)
+}
+
+func TestDestination_Write_ObjectStore(t *testing.T) {
+	t.Parallel()
+
+	is := is.New(t)
+
+	conn, err := test.GetTestConnection()
+	is.NoErr(err)
+
+	objectStore, err := test.CreateTestObjectStore(conn, "files_write")
+	is.NoErr(err)
+
+	destination := NewDestination()
+
+	err = destination.Configure(context.Background(), map[string]string{
+		config.KeyURLs:         test.TestURL,
+		config.KeyMode:         "object",
+		config.KeyObjectBucket: "files_write",
+	})
+	is.NoErr(err)
+
+	err = destination.Open(context.Background())
+	is.NoErr(err)
+
+	t.Cleanup(func() {
+		err := destination.Teardown(context.Background())
+		is.NoErr(err)
+	})
+
+	// the payload is larger than the default max payload of the server
+	large := bytes.Repeat([]byte("a"), 2*1024*1024)
+
+	written, err := destination.Write(context.Background(), []sdk.Record{
+		{
+			Operation: sdk.OperationCreate,
+			Key:       sdk.RawData("large"),
+			Payload:   sdk.Change{After: sdk.RawData(large)},
+		},
+		{
+			Operation: sdk.OperationCreate,
+			Key:       sdk.RawData("small"),
+			Payload:   sdk.Change{After: sdk.RawData("1")},
+		},
+		{
+			Operation: sdk.OperationDelete,
+			Key:       sdk.RawData("small"),
+		},
+		{
+			// deleting an object which doesn't exist isn't an error
+			Operation: sdk.OperationDelete,
+			Key:       sdk.RawData("missing"),
+		},
+		{
+			// a record without a payload puts an empty object
+			Operation: sdk.OperationCreate,
+			Key:       sdk.RawData("empty"),
+		},
+	})
+	is.NoErr(err)
+	is.Equal(written, 5)
+
+	content, err := objectStore.GetBytes("empty")
+	is.NoErr(err)
+	is.Equal(len(content), 0)
+
+	content, err = objectStore.GetBytes("large")
+	is.NoErr(err)
+	is.True(bytes.Equal(content, large))
+
+	_, err = objectStore.GetInfo("small")
+	is.True(errors.Is(err, nats.ErrObjectNotFound))
+}
+
+func TestDestination_Write_Core(t *testing.T) {
+	t.Parallel()
+
+	is := is.New(t)
+
+	conn, err := test.GetTestConnection()
+	is.NoErr(err)
+
+	// there's no stream covering the subject, the messages are received by a plain subscription
+	subscription, err := conn.SubscribeSync("core_write")
+	is.NoErr(err)
+
+	err = conn.Flush()
+	is.NoErr(err)
+
+	destination := NewDestination()
+
+	err = destination.Configure(context.Background(), map[string]string{
+		config.KeyURLs:    test.TestURL,
+		config.KeyMode:    "core",
+		config.KeySubject: "core_write",
+	})
+	is.NoErr(err)
+
+	err = destination.Open(context.Background())
+	is.NoErr(err)
+
+	t.Cleanup(func() {
+		err := destination.Teardown(context.Background())
+		is.NoErr(err)
+	})
+
+	written, err := destination.Write(context.Background(), []sdk.Record{
+		{
+			Operation: sdk.OperationCreate,
+			Payload:   sdk.Change{After: sdk.RawData("1")},
+		},
+		{
+			Operation: sdk.OperationCreate,
+			Payload:   sdk.Change{After: sdk.RawData("2")},
+		},
+	})
+	is.NoErr(err)
+	is.Equal(written, 2)
+
+	for _, want := range []string{"1", "2"} {
+		msg, err := subscription.NextMsg(time.Second)
+		is.NoErr(err)
+		is.Equal(string(msg.Data), want)
+	}
+}
+
+func TestDestination_Write_headers(t *testing.T) {
+	t.Parallel()
+
+	is := is.New(t)
+
+	conn, err := test.GetTestConnection()
+	is.NoErr(err)
+
+	subscription, err := conn.SubscribeSync("destination_write_headers")
+	is.NoErr(err)
+
+	err = conn.Flush()
+	is.NoErr(err)
+
+	destination := NewDestination()
+
+	err = destination.Configure(context.Background(), map[string]string{
+		config.KeyURLs:          test.TestURL,
+		config.KeyMode:          "core",
+		config.KeySubject:       "destination_write_headers",
+		ConfigKeyHeadersExclude: "internal.*",
+		ConfigKeyHeadersPrefix:  "X-",
+	})
+	is.NoErr(err)
+
+	err = destination.Open(context.Background())
+	is.NoErr(err)
+
+	t.Cleanup(func() {
+		err := destination.Teardown(context.Background())
+		is.NoErr(err)
+	})
+
+	written, err := destination.Write(context.Background(), []sdk.Record{
+		{
+			Operation: sdk.OperationUpdate,
+			Key:       sdk.RawData("42"),
+			Metadata: sdk.Metadata{
+				"table":         "orders",
+				"internal.hash": "f00d",
+			},
+			Payload: sdk.Change{After: sdk.RawData("1")},
+		},
+	})
+	is.NoErr(err)
+	is.Equal(written, 1)
+
+	msg, err := subscription.NextMsg(time.Second)
+	is.NoErr(err)
+	is.Equal(msg.Header.Get("X-opencdc.key"), "42")
+	is.Equal(msg.Header.Get("X-opencdc.operation"), "update")
+	is.Equal(msg.Header.Get("X-table"), "orders")
+	is.Equal(msg.Header.Get("X-internal.hash"), "")
+}
+
+func TestDestination_Write_JetStream_batch(t *testing.T) {
+	t.Parallel()
+
+	is := is.New(t)
+
+	conn, err := test.GetTestConnection()
+	is.NoErr(err)
+
+	jetstream, err := conn.JetStream()
+	is.NoErr(err)
+
+	// the message size, which includes headers, is limited, so the stream rejects large messages
+	_, err = jetstream.AddStream(&nats.StreamConfig{
+		Name:       t.Name(),
+		Subjects:   []string{"destination_write_batch"},
+		MaxMsgSize: 64,
+	})
+	is.NoErr(err)
+
+	destination := NewDestination()
+
+	err = destination.Configure(context.Background(), map[string]string{
+		config.KeyURLs:      test.TestURL,
+		config.KeySubject:   "destination_write_batch",
+		ConfigKeyMaxPending: "8",
+	})
+	is.NoErr(err)
+
+	err = destination.Open(context.Background())
+	is.NoErr(err)
+
+	t.Cleanup(func() {
+		err := destination.Teardown(context.Background())
+		is.NoErr(err)
+	})
+
+	// the batch is larger than the max pending count
+	records := make([]sdk.Record, 100)
+	for i := range records {
+		records[i] = sdk.Record{
+			Operation: sdk.OperationCreate,
+			Payload:   sdk.Change{After: sdk.RawData(fmt.Sprintf("%d", i))},
+		}
+	}
+
+	written, err := destination.Write(context.Background(), records)
+	is.NoErr(err)
+	is.Equal(written, 100)
+
+	info, err := jetstream.StreamInfo(t.Name())
+	is.NoErr(err)
+	is.Equal(info.State.Msgs, uint64(100))
+
+	// only the records before the rejected one are counted,
+	// even though the record after it is acknowledged
+	written, err = destination.Write(context.Background(), []sdk.Record{
+		{Operation: sdk.OperationCreate, Payload: sdk.Change{After: sdk.RawData("small")}},
+		{Operation: sdk.OperationCreate, Payload: sdk.Change{After: sdk.RawData("small")}},
+		{Operation: sdk.OperationCreate, Payload: sdk.Change{After: sdk.RawData(bytes.Repeat([]byte("a"), 64))}},
+		{Operation: sdk.OperationCreate, Payload: sdk.Change{After: sdk.RawData("small")}},
+	})
+	is.True(err != nil)
+	is.Equal(written, 2)
+
+	info, err = jetstream.StreamInfo(t.Name())
+	is.NoErr(err)
+	is.Equal(info.State.Msgs, uint64(103))
+}
+
+func TestDestination_Write_JetStream_noResponders(t *testing.T) {
+	t.Parallel()
+
+	is := is.New(t)
+
+	destination := NewDestination()
+
+	// there's no stream covering the subject
+	err := destination.Configure(context.Background(), map[string]string{
+		config.KeyURLs:         test.TestURL,
+		config.KeySubject:      "destination_write_no_responders",
+		ConfigKeyRetryWait:     "10ms",
+		ConfigKeyRetryAttempts: "2",
+	})
+	is.NoErr(err)
+
+	err = destination.Open(context.Background())
+	is.NoErr(err)
+
+	t.Cleanup(func() {
+		err := destination.Teardown(context.Background())
+		is.NoErr(err)
+	})
+
+	written, err := destination.Write(context.Background(), []sdk.Record{
+		{Operation: sdk.OperationCreate, Payload: sdk.Change{After: sdk.RawData("1")}},
+	})
+	is.True(errors.Is(err, nats.ErrNoResponders))
+	is.Equal(written, 0)
+}
+
+func TestDestination_Write_JetStream_noRespondersAckedNotResent(t *testing.T) {
+	t.Parallel()
+
+	is := is.New(t)
+
+	conn, err := test.GetTestConnection()
+	is.NoErr(err)
+
+	jetstream, err := conn.JetStream()
+	is.NoErr(err)
+
+	// the stream covers only the "a" subject, so the "b" one has no responders
+	_, err = jetstream.AddStream(&nats.StreamConfig{
+		Name:     t.Name(),
+		Subjects: []string{"destination_write_retry.a"},
+	})
+	is.NoErr(err)
+
+	destination := NewDestination()
+
+	err = destination.Configure(context.Background(), map[string]string{
+		config.KeyURLs:            test.TestURL,
+		config.KeySubjectTemplate: "destination_write_retry.{{.Metadata.t}}",
+		ConfigKeyRetryWait:        "10ms",
+		ConfigKeyRetryAttempts:    "2",
+	})
+	is.NoErr(err)
+
+	err = destination.Open(context.Background())
+	is.NoErr(err)
+
+	t.Cleanup(func() {
+		err := destination.Teardown(context.Background())
+		is.NoErr(err)
+	})
+
+	written, err := destination.Write(context.Background(), []sdk.Record{
+		{Operation: sdk.OperationCreate, Metadata: sdk.Metadata{"t": "a"}, Payload: sdk.Change{After: sdk.RawData("1")}},
+		{Operation: sdk.OperationCreate, Metadata: sdk.Metadata{"t": "b"}, Payload: sdk.Change{After: sdk.RawData("2")}},
+		{Operation: sdk.OperationCreate, Metadata: sdk.Metadata{"t": "a"}, Payload: sdk.Change{After: sdk.RawData("3")}},
+	})
+	is.True(errors.Is(err, nats.ErrNoResponders))
+	is.Equal(written, 1)
+
+	// only the failed record is retried, the acknowledged ones are stored once
+	info, err := jetstream.StreamInfo(t.Name())
+	is.NoErr(err)
+	is.Equal(info.State.Msgs, uint64(2))
+}
+
+func TestDestination_Write_subjectTemplate(t *testing.T) {
+	t.Parallel()
+
+	is := is.New(t)
+
+	conn, err := test.GetTestConnection()
+	is.NoErr(err)
+
+	subscription, err := conn.SubscribeSync("destination_write_template.>")
+	is.NoErr(err)
+
+	err = conn.Flush()
+	is.NoErr(err)
+
+	destination := NewDestination()
+
+	err = destination.Configure(context.Background(), map[string]string{
+		config.KeyURLs:            test.TestURL,
+		config.KeyMode:            "core",
+		config.KeySubjectTemplate: "destination_write_template.{{.Metadata.tenant}}.{{.Operation}}",
+	})
+	is.NoErr(err)
+
+	err = destination.Open(context.Background())
+	is.NoErr(err)
+
+	t.Cleanup(func() {
+		err := destination.Teardown(context.Background())
+		is.NoErr(err)
+	})
+
+	// the third record has no tenant, so the records before it are written only
+	written, err := destination.Write(context.Background(), []sdk.Record{
+		{
+			Operation: sdk.OperationCreate,
+			Metadata:  sdk.Metadata{"tenant": "acme"},
+			Payload:   sdk.Change{After: sdk.RawData("1")},
+		},
+		{
+			Operation: sdk.OperationUpdate,
+			Metadata:  sdk.Metadata{"tenant": "globex"},
+			Payload:   sdk.Change{After: sdk.RawData("2")},
+		},
+		{
+			Operation: sdk.OperationCreate,
+			Metadata:  sdk.Metadata{},
+			Payload:   sdk.Change{After: sdk.RawData("3")},
+		},
+	})
+	is.True(err != nil)
+	is.Equal(written, 2)
+
+	for _, want := range []string{
+		"destination_write_template.acme.create",
+		"destination_write_template.globex.update",
+	} {
+		msg, err := subscription.NextMsg(time.Second)
+		is.NoErr(err)
+		is.Equal(msg.Subject, want)
+	}
+}
+
+func TestDestination_Write_JetStream_msgID(t *testing.T) {
+	t.Parallel()
+
+	is := is.New(t)
+
+	conn, err := test.GetTestConnection()
+	is.NoErr(err)
+
+	jetstream, err := conn.JetStream()
+	is.NoErr(err)
+
+	_, err = jetstream.AddStream(&nats.StreamConfig{
+		Name:       t.Name(),
+		Subjects:   []string{"destination_write_msg_id"},
+		Duplicates: time.Minute,
+	})
+	is.NoErr(err)
+
+	destination := NewDestination()
+
+	err = destination.Configure(context.Background(), map[string]string{
+		config.KeyURLs:       test.TestURL,
+		config.KeySubject:    "destination_write_msg_id",
+		ConfigKeyMsgIDSource: "position",
+	})
+	is.NoErr(err)
+
+	err = destination.Open(context.Background())
+	is.NoErr(err)
+
+	t.Cleanup(func() {
+		err := destination.Teardown(context.Background())
+		is.NoErr(err)
+	})
+
+	records := []sdk.Record{
+		{Position: sdk.Position("1"), Operation: sdk.OperationCreate, Payload: sdk.Change{After: sdk.RawData("1")}},
+		{Position: sdk.Position("2"), Operation: sdk.OperationCreate, Payload: sdk.Change{After: sdk.RawData("2")}},
+	}
+
+	written, err := destination.Write(context.Background(), records)
+	is.NoErr(err)
+	is.Equal(written, 2)
+
+	// the records are redelivered, e.g. after a restart, and the stream discards them
+	written, err = destination.Write(context.Background(), records)
+	is.NoErr(err)
+	is.Equal(written, 2)
+
+	info, err := jetstream.StreamInfo(t.Name())
+	is.NoErr(err)
+	is.Equal(info.State.Msgs, uint64(2))
+}
+
+func TestDestination_Write_payloadFormat(t *testing.T) {
+	t.Parallel()
+
+	is := is.New(t)
+
+	conn, err := test.GetTestConnection()
+	is.NoErr(err)
+
+	subscription, err := conn.SubscribeSync("destination_write_payload_format")
+	is.NoErr(err)
+
+	err = conn.Flush()
+	is.NoErr(err)
+
+	destination := NewDestination()
+
+	err = destination.Configure(context.Background(), map[string]string{
+		config.KeyURLs:         test.TestURL,
+		config.KeyMode:         "core",
+		config.KeySubject:      "destination_write_payload_format",
+		ConfigKeyPayloadFormat: "key-only-on-delete",
+	})
+	is.NoErr(err)
+
+	err = destination.Open(context.Background())
+	is.NoErr(err)
+
+	t.Cleanup(func() {
+		err := destination.Teardown(context.Background())
+		is.NoErr(err)
+	})
+
+	written, err := destination.Write(context.Background(), []sdk.Record{
+		{
+			Operation: sdk.OperationUpdate,
+			Key:       sdk.RawData("42"),
+			Payload:   sdk.Change{After: sdk.RawData(`{"status":"paid"}`)},
+		},
+		{
+			Operation: sdk.OperationDelete,
+			Key:       sdk.RawData("42"),
+			Payload:   sdk.Change{Before: sdk.RawData(`{"status":"paid"}`)},
+		},
+	})
+	is.NoErr(err)
+	is.Equal(written, 2)
+
+	msg, err := subscription.NextMsg(time.Second)
+	is.NoErr(err)
+	is.Equal(string(msg.Data), `{"status":"paid"}`)
+
+	msg, err = subscription.NextMsg(time.Second)
+	is.NoErr(err)
+	is.Equal(string(msg.Data), "42")
+	is.Equal(msg.Header.Get(message.HeaderOperation), "delete")
+}
+
+func TestDestination_Open_provisionStream(t *testing.T) {
+	t.Parallel()
+
+	is := is.New(t)
+
+	conn, err := test.GetTestConnection()
+	is.NoErr(err)
+
+	jetstream, err := conn.JetStream()
+	is.NoErr(err)
+
+	cfg := map[string]string{
+		config.KeyURLs:         test.TestURL,
+		config.KeySubject:      "destination_provision_stream",
+		config.KeyStreamName:   t.Name(),
+		config.KeyStreamMaxAge: "1h",
+	}
+
+	// the stream doesn't exist, so it's created
+	destination := NewDestination()
+
+	err = destination.Configure(context.Background(), cfg)
+	is.NoErr(err)
+
+	err = destination.Open(context.Background())
+	is.NoErr(err)
+
+	written, err := destination.Write(context.Background(), []sdk.Record{
+		{Operation: sdk.OperationCreate, Payload: sdk.Change{After: sdk.RawData("1")}},
+	})
+	is.NoErr(err)
+	is.Equal(written, 1)
+
+	err = destination.Teardown(context.Background())
+	is.NoErr(err)
+
+	info, err := jetstream.StreamInfo(t.Name())
+	is.NoErr(err)
+	is.Equal(info.Config.Subjects, []string{"destination_provision_stream"})
+	is.Equal(info.Config.MaxAge, time.Hour)
+	is.Equal(info.State.Msgs, uint64(1))
+
+	// the stream differs from the config, so the destination fails to open
+	cfg[config.KeyStreamMaxAge] = "2h"
+
+	destination = NewDestination()
+
+	err = destination.Configure(context.Background(), cfg)
+	is.NoErr(err)
+
+	err = destination.Open(context.Background())
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), config.KeyStreamMaxAge))
+
+	// the update is allowed, so the stream is updated
+	cfg[config.KeyStreamUpdate] = "true"
+
+	destination = NewDestination()
+
+	err = destination.Configure(context.Background(), cfg)
+	is.NoErr(err)
+
+	err = destination.Open(context.Background())
+	is.NoErr(err)
+
+	err = destination.Teardown(context.Background())
+	is.NoErr(err)
+
+	info, err = jetstream.StreamInfo(t.Name())
+	is.NoErr(err)
+	is.Equal(info.Config.MaxAge, 2*time.Hour)
+	is.Equal(info.State.Msgs, uint64(1))
+}
+
+func TestDestination_Open_provisionStream_explicitFields(t *testing.T) {
+	t.Parallel()
+
+	is := is.New(t)
+
+	conn, err := test.GetTestConnection()
+	is.NoErr(err)
+
+	jetstream, err := conn.JetStream()
+	is.NoErr(err)
+
+	_, err = jetstream.AddStream(&nats.StreamConfig{
+		Name:     t.Name(),
+		Subjects: []string{"destination_provision_explicit"},
+		MaxAge:   time.Hour,
+		MaxBytes: 1 << 20,
+	})
+	is.NoErr(err)
+
+	// only the name is set, so the defaults of the other fields are not compared with the stream
+	cfg := map[string]string{
+		config.KeyURLs:       test.TestURL,
+		config.KeySubject:    "destination_provision_explicit",
+		config.KeyStreamName: t.Name(),
+	}
+
+	destination := NewDestination()
+
+	err = destination.Configure(context.Background(), cfg)
+	is.NoErr(err)
+
+	err = destination.Open(context.Background())
+	is.NoErr(err)
+
+	err = destination.Teardown(context.Background())
+	is.NoErr(err)
+
+	// only the subjects are updated, the other fields are kept
+	cfg[config.KeyStreamSubjects] = "destination_provision_explicit, destination_provision_explicit.more"
+	cfg[config.KeyStreamUpdate] = "true"
+
+	destination = NewDestination()
+
+	err = destination.Configure(context.Background(), cfg)
+	is.NoErr(err)
+
+	err = destination.Open(context.Background())
+	is.NoErr(err)
+
+	err = destination.Teardown(context.Background())
+	is.NoErr(err)
+
+	info, err := jetstream.StreamInfo(t.Name())
+	is.NoErr(err)
+	is.Equal(info.Config.Subjects, []string{"destination_provision_explicit", "destination_provision_explicit.more"})
+	is.Equal(info.Config.MaxAge, time.Hour)
+	is.Equal(info.Config.MaxBytes, int64(1<<20))
+}
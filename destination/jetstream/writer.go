@@ -15,73 +15,206 @@
 package jetstream
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"sync"
 	"time"
 
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/message"
 	sdk "github.com/conduitio/conduit-connector-sdk"
 	"github.com/nats-io/nats.go"
 )
 
+// ackTimeout is the maximum amount of time Write waits for the acknowledgement of each message,
+// the same as the default timeout of a synchronous publish.
+const ackTimeout = time.Second * 5
+
 // Writer implements a JetStream writer.
-// It writes messages asynchronously.
+// It publishes records asynchronously in windows of at most the max pending messages,
+// and waits for their acknowledgements before publishing the next window.
 type Writer struct {
-	sync.Mutex
-
-	conn        *nats.Conn
-	subject     string
-	jetstream   nats.JetStreamContext
-	publishOpts []nats.PubOpt
+	conn          *nats.Conn
+	builder       *message.Builder
+	jetstream     nats.JetStreamContext
+	retryWait     time.Duration
+	retryAttempts int
+	maxPending    int
 }
 
 // WriterParams is an incoming params for the NewWriter function.
 type WriterParams struct {
-	Conn          *nats.Conn
-	Subject       string
+	Conn *nats.Conn
+	// Builder builds messages from records.
+	Builder       *message.Builder
 	RetryWait     time.Duration
 	RetryAttempts int
-}
-
-// getPublishOptions returns a NATS publish options based on the WriterParams's fields.
-func (p WriterParams) getPublishOptions() []nats.PubOpt {
-	var opts []nats.PubOpt
-
-	if p.RetryWait != 0 {
-		opts = append(opts, nats.RetryWait(p.RetryWait))
-	}
-
-	if p.RetryAttempts != 0 {
-		opts = append(opts, nats.RetryAttempts(p.RetryAttempts))
-	}
-
-	return opts
+	// MaxPending is the maximum number of published messages waiting for acknowledgements,
+	// larger batches are published in several windows.
+	MaxPending int
 }
 
 // NewWriter creates new instance of the Writer.
 func NewWriter(params WriterParams) (*Writer, error) {
-	jetstream, err := params.Conn.JetStream()
+	jetstream, err := params.Conn.JetStream(nats.PublishAsyncMaxPending(params.MaxPending))
 	if err != nil {
 		return nil, fmt.Errorf("get jetstream context: %w", err)
 	}
 
 	return &Writer{
-		conn:        params.Conn,
-		subject:     params.Subject,
-		jetstream:   jetstream,
-		publishOpts: params.getPublishOptions(),
+		conn:          params.Conn,
+		builder:       params.Builder,
+		jetstream:     jetstream,
+		retryWait:     params.RetryWait,
+		retryAttempts: params.RetryAttempts,
+		maxPending:    params.MaxPending,
 	}, nil
 }
 
-// Write synchronously writes a record.
-func (w *Writer) Write(record sdk.Record) error {
-	_, err := w.jetstream.Publish(w.subject, record.Payload.After.Bytes(), w.publishOpts...)
+// Write publishes records asynchronously and waits for their acknowledgements.
+// It returns the number of the first records which were all acknowledged,
+// records after the first failed one aren't counted as written even if they were acknowledged.
+// If there were no responders for a record, only that record is published again,
+// so it's stored after the records which followed it and were already acknowledged.
+func (w *Writer) Write(ctx context.Context, records []sdk.Record) (int, error) {
+	var written int
+
+	for written < len(records) {
+		end := written + w.maxPending
+		if end > len(records) {
+			end = len(records)
+		}
+
+		acked, err := w.publishWindow(ctx, records[written:end])
+		written += acked
+
+		if err != nil {
+			w.waitPending(ctx)
+
+			return written, err
+		}
+	}
+
+	return written, nil
+}
+
+// publishWindow publishes records asynchronously and returns the number of the first records
+// which were acknowledged.
+func (w *Writer) publishWindow(ctx context.Context, records []sdk.Record) (int, error) {
+	futures := make([]nats.PubAckFuture, 0, len(records))
+
+	var publishErr error
+
+	for _, record := range records {
+		msg, err := w.builder.Build(record)
+		if err != nil {
+			publishErr = fmt.Errorf("build message: %w", err)
+
+			break
+		}
+
+		future, err := w.jetstream.PublishMsgAsync(msg)
+		if err != nil {
+			publishErr = fmt.Errorf("publish async: %w", err)
+
+			break
+		}
+
+		futures = append(futures, future)
+	}
+
+	for i, future := range futures {
+		err := w.waitAck(ctx, future)
+		if errors.Is(err, nats.ErrNoResponders) {
+			err = w.republish(ctx, future.Msg())
+		}
+
+		if err != nil {
+			return i, err
+		}
+	}
+
+	return len(futures), publishErr
+}
+
+// waitAck waits for the acknowledgement of an asynchronously published message.
+func (w *Writer) waitAck(ctx context.Context, future nats.PubAckFuture) error {
+	timer := time.NewTimer(ackTimeout)
+	defer timer.Stop()
+
+	select {
+	case ack := <-future.Ok():
+		logDuplicate(ctx, ack, future.Msg())
+
+		return nil
+
+	case err := <-future.Err():
+		return fmt.Errorf("publish: %w", err)
+
+	case <-timer.C:
+		return fmt.Errorf("wait for acknowledgement: %w", nats.ErrTimeout)
+
+	case <-ctx.Done():
+		return fmt.Errorf("wait for acknowledgement: %w", ctx.Err())
+	}
+}
+
+// republish publishes a message, which had no responders, synchronously after the retry wait,
+// at most the retry attempts times.
+func (w *Writer) republish(ctx context.Context, msg *nats.Msg) error {
+	err := nats.ErrNoResponders
+
+	for attempt := 0; attempt < w.retryAttempts && errors.Is(err, nats.ErrNoResponders); attempt++ {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("retry publish: %w", ctx.Err())
+		case <-time.After(w.retryWait):
+		}
+
+		var ack *nats.PubAck
+
+		// the reply subject of the asynchronous publish isn't reused
+		ack, err = w.jetstream.PublishMsg(
+			&nats.Msg{Subject: msg.Subject, Header: msg.Header, Data: msg.Data}, nats.AckWait(ackTimeout),
+		)
+		switch {
+		case err == nil:
+			logDuplicate(ctx, ack, msg)
+		case errors.Is(err, nats.ErrNoStreamResponse):
+			// a synchronous publish reports no responders as no stream response
+			err = nats.ErrNoResponders
+		}
+	}
+
 	if err != nil {
-		return fmt.Errorf("publish sync: %w", err)
+		return fmt.Errorf("publish: %w", err)
 	}
 
 	return nil
 }
 
+// waitPending waits until the messages left after a failed window are acknowledged,
+// so that they don't count towards the max pending messages of the next Write.
+func (w *Writer) waitPending(ctx context.Context) {
+	select {
+	case <-w.jetstream.PublishAsyncComplete():
+	case <-ctx.Done():
+	case <-time.After(ackTimeout):
+	}
+}
+
+// logDuplicate reports a message which JetStream acknowledged as a duplicate without storing it.
+func logDuplicate(ctx context.Context, ack *nats.PubAck, msg *nats.Msg) {
+	if !ack.Duplicate {
+		return
+	}
+
+	sdk.Logger(ctx).Debug().
+		Str("stream", ack.Stream).
+		Uint64("sequence", ack.Sequence).
+		Str("msg_id", msg.Header.Get(nats.MsgIdHdr)).
+		Msg("message is a duplicate, discarded by the stream")
+}
+
 // Close closes the underlying NATS connection.
 func (w *Writer) Close() error {
 	if w.conn != nil {
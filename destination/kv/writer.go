@@ -0,0 +1,223 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/message"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/nats-io/nats.go"
+)
+
+// DeleteOperation defines how delete records are applied to a bucket.
+type DeleteOperation int
+
+const (
+	// DeleteOperationDelete puts a delete marker and keeps the history of a key.
+	DeleteOperationDelete DeleteOperation = iota
+	// DeleteOperationPurge puts a purge marker and removes the history of a key.
+	DeleteOperationPurge
+)
+
+// Writer implements a Key-Value bucket writer.
+// It puts entries synchronously.
+type Writer struct {
+	conn            *nats.Conn
+	keyValue        nats.KeyValue
+	deleteOperation DeleteOperation
+	// revisions holds the last known revisions of keys,
+	// it's used only if the optimistic concurrency is enabled
+	revisions map[string]uint64
+}
+
+// WriterParams is an incoming params for the NewWriter function.
+type WriterParams struct {
+	Conn            *nats.Conn
+	Bucket          string
+	DeleteOperation DeleteOperation
+	// OptimisticConcurrency makes the Writer fail if a key was modified
+	// by someone else since the Writer saw it last time.
+	OptimisticConcurrency bool
+}
+
+// NewWriter creates new instance of the Writer.
+func NewWriter(params WriterParams) (*Writer, error) {
+	jetstream, err := params.Conn.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("get jetstream context: %w", err)
+	}
+
+	keyValue, err := jetstream.KeyValue(params.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("get key value bucket %q: %w", params.Bucket, err)
+	}
+
+	writer := &Writer{
+		conn:            params.Conn,
+		keyValue:        keyValue,
+		deleteOperation: params.DeleteOperation,
+	}
+
+	if params.OptimisticConcurrency {
+		writer.revisions = make(map[string]uint64)
+	}
+
+	return writer, nil
+}
+
+// Write synchronously applies records to the bucket one by one,
+// it stops at the first record which fails and returns the number of records applied before it.
+func (w *Writer) Write(_ context.Context, records []sdk.Record) (int, error) {
+	for i, record := range records {
+		if err := w.write(record); err != nil {
+			return i, err
+		}
+	}
+
+	return len(records), nil
+}
+
+// write synchronously applies a record to the bucket.
+// Delete records delete or purge the record key, and all other records put the record payload.
+func (w *Writer) write(record sdk.Record) error {
+	if record.Key == nil || len(record.Key.Bytes()) == 0 {
+		return errors.New("record key is empty")
+	}
+
+	key := string(record.Key.Bytes())
+
+	if record.Operation == sdk.OperationDelete {
+		if err := w.delete(key); err != nil {
+			return fmt.Errorf("delete key %q: %w", key, err)
+		}
+
+		return nil
+	}
+
+	if err := w.put(key, record); err != nil {
+		return fmt.Errorf("put key %q: %w", key, err)
+	}
+
+	return nil
+}
+
+// put puts the record payload under the key, a record without a payload puts an empty value.
+// With the optimistic concurrency a create record creates the key, which must not exist,
+// and other records update the key, which must be at the last known revision.
+func (w *Writer) put(key string, record sdk.Record) error {
+	value := message.DataBytes(record.Payload.After)
+
+	if w.revisions == nil {
+		_, err := w.keyValue.Put(key, value)
+
+		return err
+	}
+
+	var (
+		revision uint64
+		err      error
+	)
+
+	if record.Operation == sdk.OperationCreate {
+		revision, err = w.keyValue.Create(key, value)
+	} else {
+		revision, err = w.update(key, value)
+	}
+
+	if err != nil {
+		// the revision is unknown now, so it's fetched again next time
+		delete(w.revisions, key)
+
+		return err
+	}
+
+	w.revisions[key] = revision
+
+	return nil
+}
+
+// update updates the key expecting it to be at the last known revision,
+// or creates it if it doesn't exist.
+func (w *Writer) update(key string, value []byte) (uint64, error) {
+	revision, ok, err := w.lastRevision(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if !ok {
+		return w.keyValue.Create(key, value)
+	}
+
+	return w.keyValue.Update(key, value, revision)
+}
+
+// delete deletes or purges the key depending on the delete operation.
+// With the optimistic concurrency the key must be at the last known revision.
+func (w *Writer) delete(key string) error {
+	var opts []nats.DeleteOpt
+
+	if w.revisions != nil {
+		revision, ok, err := w.lastRevision(key)
+		if err != nil {
+			return err
+		}
+
+		// there is nothing to delete
+		if !ok {
+			return nil
+		}
+
+		opts = append(opts, nats.LastRevision(revision))
+
+		delete(w.revisions, key)
+	}
+
+	if w.deleteOperation == DeleteOperationPurge {
+		return w.keyValue.Purge(key, opts...)
+	}
+
+	return w.keyValue.Delete(key, opts...)
+}
+
+// lastRevision returns the last known revision of the key, or fetches it from the bucket.
+// It returns false if the key doesn't exist.
+func (w *Writer) lastRevision(key string) (uint64, bool, error) {
+	if revision, ok := w.revisions[key]; ok {
+		return revision, true, nil
+	}
+
+	entry, err := w.keyValue.Get(key)
+	if err != nil {
+		if errors.Is(err, nats.ErrKeyNotFound) {
+			return 0, false, nil
+		}
+
+		return 0, false, fmt.Errorf("get key: %w", err)
+	}
+
+	return entry.Revision(), true, nil
+}
+
+// Close closes the underlying NATS connection.
+func (w *Writer) Close() error {
+	if w.conn != nil {
+		w.conn.Close()
+	}
+
+	return nil
+}
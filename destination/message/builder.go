@@ -0,0 +1,176 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/subject"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// HeaderKey is a name of a header that holds a record key.
+	HeaderKey = "opencdc.key"
+	// HeaderOperation is a name of a header that holds a record operation.
+	HeaderOperation = "opencdc.operation"
+)
+
+// Builder builds messages from records.
+type Builder struct {
+	subject string
+	// subjectTemplate renders a subject for each record instead of the subject if it's set
+	subjectTemplate *subject.Template
+	// headerPrefix is prepended to header names
+	headerPrefix string
+	// includeHeaders and excludeHeaders are patterns of header names which are added to messages,
+	// all headers are added if the includeHeaders is empty
+	includeHeaders []string
+	excludeHeaders []string
+	// payloadFormat defines which parts of a record make up a message payload
+	payloadFormat PayloadFormat
+	// msgID returns a message ID for each record, message IDs are not set if it's nil
+	msgID msgIDExtractor
+}
+
+// BuilderParams is an incoming params for the NewBuilder function.
+type BuilderParams struct {
+	Subject string
+	// SubjectTemplate renders a subject for each record, it's used instead of the Subject if it's set.
+	SubjectTemplate *subject.Template
+	// HeaderPrefix is prepended to header names.
+	HeaderPrefix string
+	// IncludeHeaders is a list of patterns of header names which are added to messages,
+	// if it's empty, all headers are added.
+	IncludeHeaders []string
+	// ExcludeHeaders is a list of patterns of header names which are not added to messages.
+	ExcludeHeaders []string
+	// PayloadFormat defines which parts of a record make up a message payload.
+	PayloadFormat PayloadFormat
+	// MsgIDSource defines where message IDs are taken from.
+	MsgIDSource MsgIDSource
+}
+
+// NewBuilder creates new instance of the Builder.
+func NewBuilder(params BuilderParams) (*Builder, error) {
+	for _, patterns := range [][]string{params.IncludeHeaders, params.ExcludeHeaders} {
+		for _, pattern := range patterns {
+			if err := ValidatePattern(pattern); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	msgID, err := newMsgIDExtractor(params.MsgIDSource)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Builder{
+		subject:         params.Subject,
+		subjectTemplate: params.SubjectTemplate,
+		headerPrefix:    params.HeaderPrefix,
+		includeHeaders:  params.IncludeHeaders,
+		excludeHeaders:  params.ExcludeHeaders,
+		payloadFormat:   params.PayloadFormat,
+		msgID:           msgID,
+	}, nil
+}
+
+// ValidatePattern checks that a pattern of header names is well-formed.
+// Patterns use the path.Match syntax, e.g. opencdc.* matches all OpenCDC metadata fields.
+func ValidatePattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid header pattern %q: %w", pattern, err)
+	}
+
+	return nil
+}
+
+// Build builds a message from the record.
+// The message headers hold the record key, operation and metadata, and the message ID if its source is set.
+func (b *Builder) Build(record sdk.Record) (*nats.Msg, error) {
+	msgSubject, err := b.subjectFor(record)
+	if err != nil {
+		return nil, fmt.Errorf("get subject: %w", err)
+	}
+
+	msg := nats.NewMsg(msgSubject)
+
+	msg.Data, err = b.payloadFormat.payloadFor(record)
+	if err != nil {
+		return nil, fmt.Errorf("get payload: %w", err)
+	}
+
+	for name, value := range record.Metadata {
+		b.setHeader(msg.Header, name, value)
+	}
+
+	// the key and operation are set after the metadata so they cannot be overridden
+	if record.Key != nil {
+		b.setHeader(msg.Header, HeaderKey, string(record.Key.Bytes()))
+	}
+
+	b.setHeader(msg.Header, HeaderOperation, record.Operation.String())
+
+	// the message ID is neither filtered nor prefixed, as JetStream looks for the exact header
+	if b.msgID != nil {
+		msgID, err := b.msgID(record)
+		if err != nil {
+			return nil, fmt.Errorf("get message id: %w", err)
+		}
+
+		msg.Header.Set(nats.MsgIdHdr, msgID)
+	}
+
+	return msg, nil
+}
+
+// subjectFor returns a subject the record is published to.
+func (b *Builder) subjectFor(record sdk.Record) (string, error) {
+	if b.subjectTemplate == nil {
+		return b.subject, nil
+	}
+
+	return b.subjectTemplate.Render(record)
+}
+
+// setHeader sets the prefixed header if its name is included and not excluded.
+// Header names are case-sensitive, so they're set as is.
+func (b *Builder) setHeader(header nats.Header, name, value string) {
+	if len(b.includeHeaders) > 0 && !matchAny(b.includeHeaders, name) {
+		return
+	}
+
+	if matchAny(b.excludeHeaders, name) {
+		return
+	}
+
+	header[b.headerPrefix+name] = []string{value}
+}
+
+// matchAny checks whether the name matches any of the patterns.
+// The patterns are validated when the Builder is created, so match errors are not possible.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
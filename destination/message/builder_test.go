@@ -0,0 +1,138 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/subject"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/nats-io/nats.go"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	t.Parallel()
+
+	record := sdk.Record{
+		Operation: sdk.OperationUpdate,
+		Key:       sdk.RawData("42"),
+		Metadata: sdk.Metadata{
+			"opencdc.readAt":        "1661860800000000000",
+			"conduit.source.plugin": "builtin:postgres",
+			"table":                 "orders",
+		},
+		Payload: sdk.Change{
+			After: sdk.RawData(`{"id":42}`),
+		},
+	}
+
+	tests := []struct {
+		name        string
+		params      BuilderParams
+		wantSubject string
+		wantHeader  nats.Header
+	}{
+		{
+			name: "success, all headers",
+			params: BuilderParams{
+				Subject: "orders",
+			},
+			wantSubject: "orders",
+			wantHeader: nats.Header{
+				HeaderKey:               []string{"42"},
+				HeaderOperation:         []string{"update"},
+				"opencdc.readAt":        []string{"1661860800000000000"},
+				"conduit.source.plugin": []string{"builtin:postgres"},
+				"table":                 []string{"orders"},
+			},
+		},
+		{
+			name: "success, included and excluded headers with a prefix",
+			params: BuilderParams{
+				Subject:        "orders",
+				HeaderPrefix:   "x-",
+				IncludeHeaders: []string{"opencdc.*", "table"},
+				ExcludeHeaders: []string{"opencdc.readAt"},
+			},
+			wantSubject: "orders",
+			wantHeader: nats.Header{
+				"x-" + HeaderKey:       []string{"42"},
+				"x-" + HeaderOperation: []string{"update"},
+				"x-table":              []string{"orders"},
+			},
+		},
+		{
+			name: "success, subject template",
+			params: BuilderParams{
+				SubjectTemplate: mustTemplate(t, "orders.{{.Metadata.table}}.{{.Operation}}"),
+				IncludeHeaders:  []string{HeaderOperation},
+			},
+			wantSubject: "orders.orders.update",
+			wantHeader: nats.Header{
+				HeaderOperation: []string{"update"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			builder, err := NewBuilder(tt.params)
+			if err != nil {
+				t.Fatalf("NewBuilder() error = %v", err)
+			}
+
+			msg, err := builder.Build(record)
+			if err != nil {
+				t.Fatalf("Builder.Build() error = %v", err)
+			}
+
+			if msg.Subject != tt.wantSubject {
+				t.Errorf("Builder.Build() subject = %v, want %v", msg.Subject, tt.wantSubject)
+			}
+
+			if !reflect.DeepEqual(msg.Header, tt.wantHeader) {
+				t.Errorf("Builder.Build() header = %v, want %v", msg.Header, tt.wantHeader)
+			}
+
+			if string(msg.Data) != `{"id":42}` {
+				t.Errorf("Builder.Build() data = %s, want %s", msg.Data, `{"id":42}`)
+			}
+		})
+	}
+}
+
+func TestNewBuilder_invalidPattern(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewBuilder(BuilderParams{ExcludeHeaders: []string{"opencdc.["}}); err == nil {
+		t.Errorf("NewBuilder() error = nil, want an error")
+	}
+}
+
+func mustTemplate(t *testing.T, text string) *subject.Template {
+	t.Helper()
+
+	tmpl, err := subject.NewTemplate(text)
+	if err != nil {
+		t.Fatalf("NewTemplate() error = %v", err)
+	}
+
+	return tmpl
+}
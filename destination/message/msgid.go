@@ -0,0 +1,115 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/subject"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// MsgIDSourceType defines where the Builder takes a message ID from.
+type MsgIDSourceType int
+
+const (
+	// MsgIDSourceNone doesn't set message IDs.
+	MsgIDSourceNone MsgIDSourceType = iota
+	// MsgIDSourceKey takes a message ID from a record key.
+	MsgIDSourceKey
+	// MsgIDSourcePosition takes a message ID from a hash of a record position.
+	MsgIDSourcePosition
+	// MsgIDSourceMetadata takes a message ID from a record metadata field.
+	MsgIDSourceMetadata
+	// MsgIDSourceTemplate renders a message ID from a template.
+	MsgIDSourceTemplate
+)
+
+// MsgIDSource describes how the Builder gets a message ID from a record.
+// JetStream uses message IDs to discard messages published more than once within the stream's duplicate window.
+type MsgIDSource struct {
+	Type MsgIDSourceType
+	// MetadataField is a name of a metadata field that holds a message ID.
+	MetadataField string
+	// Template is a Go text/template a message ID is rendered from.
+	Template string
+}
+
+// msgIDExtractor returns a message ID for a record.
+type msgIDExtractor func(record sdk.Record) (string, error)
+
+// newMsgIDExtractor creates an extractor of message IDs for the source,
+// it returns nil if message IDs are not set.
+func newMsgIDExtractor(source MsgIDSource) (msgIDExtractor, error) {
+	switch source.Type {
+	case MsgIDSourceNone:
+		return nil, nil
+
+	case MsgIDSourceKey:
+		return func(record sdk.Record) (string, error) {
+			if record.Key == nil || len(record.Key.Bytes()) == 0 {
+				return "", errors.New("record key is empty")
+			}
+
+			return string(record.Key.Bytes()), nil
+		}, nil
+
+	case MsgIDSourcePosition:
+		return func(record sdk.Record) (string, error) {
+			if len(record.Position) == 0 {
+				return "", errors.New("record position is empty")
+			}
+
+			hash := sha256.Sum256(record.Position)
+
+			return hex.EncodeToString(hash[:]), nil
+		}, nil
+
+	case MsgIDSourceMetadata:
+		return func(record sdk.Record) (string, error) {
+			value := record.Metadata[source.MetadataField]
+			if value == "" {
+				return "", fmt.Errorf("record metadata field %q is empty", source.MetadataField)
+			}
+
+			return value, nil
+		}, nil
+
+	case MsgIDSourceTemplate:
+		template, err := subject.NewTemplate(source.Template)
+		if err != nil {
+			return nil, fmt.Errorf("parse message id template: %w", err)
+		}
+
+		return func(record sdk.Record) (string, error) {
+			value, err := template.Execute(record)
+			if err != nil {
+				return "", err
+			}
+
+			if value == "" {
+				return "", errors.New("rendered message id is empty")
+			}
+
+			return value, nil
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown message id source type %d", source.Type)
+	}
+}
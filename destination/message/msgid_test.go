@@ -0,0 +1,128 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"testing"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/nats-io/nats.go"
+)
+
+func TestBuilder_Build_msgID(t *testing.T) {
+	t.Parallel()
+
+	record := sdk.Record{
+		Position:  sdk.Position("pos-1"),
+		Operation: sdk.OperationCreate,
+		Key:       sdk.RawData("42"),
+		Metadata:  sdk.Metadata{"event.id": "e-7"},
+		Payload:   sdk.Change{After: sdk.StructuredData{"id": 42}},
+	}
+
+	tests := []struct {
+		name    string
+		source  MsgIDSource
+		record  sdk.Record
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "success, none",
+			source: MsgIDSource{Type: MsgIDSourceNone},
+			record: record,
+			want:   "",
+		},
+		{
+			name:   "success, key",
+			source: MsgIDSource{Type: MsgIDSourceKey},
+			record: record,
+			want:   "42",
+		},
+		{
+			name:   "success, position",
+			source: MsgIDSource{Type: MsgIDSourcePosition},
+			record: record,
+			want:   "db89260c1f2d82be126f4637e5ccc2430c0bf0f5a0564bf814746e65ae4b24f5",
+		},
+		{
+			name:   "success, metadata",
+			source: MsgIDSource{Type: MsgIDSourceMetadata, MetadataField: "event.id"},
+			record: record,
+			want:   "e-7",
+		},
+		{
+			name:   "success, template",
+			source: MsgIDSource{Type: MsgIDSourceTemplate, Template: "{{.Operation}}-{{.Payload.id}}"},
+			record: record,
+			want:   "create-42",
+		},
+		{
+			name:    "fail, empty key",
+			source:  MsgIDSource{Type: MsgIDSourceKey},
+			record:  sdk.Record{Payload: sdk.Change{After: sdk.RawData("{}")}},
+			wantErr: true,
+		},
+		{
+			name:    "fail, missing metadata field",
+			source:  MsgIDSource{Type: MsgIDSourceMetadata, MetadataField: "missing"},
+			record:  record,
+			wantErr: true,
+		},
+		{
+			name:    "fail, template renders an empty id",
+			source:  MsgIDSource{Type: MsgIDSourceTemplate, Template: "{{if false}}id{{end}}"},
+			record:  record,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			builder, err := NewBuilder(BuilderParams{Subject: "orders", MsgIDSource: tt.source})
+			if err != nil {
+				t.Fatalf("NewBuilder() error = %v", err)
+			}
+
+			msg, err := builder.Build(tt.record)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Builder.Build() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if got := msg.Header.Get(nats.MsgIdHdr); got != tt.want {
+				t.Errorf("Builder.Build() message id = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewBuilder_invalidMsgIDTemplate(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewBuilder(BuilderParams{MsgIDSource: MsgIDSource{Type: MsgIDSourceTemplate, Template: "{{.Key"}})
+	if err == nil {
+		t.Errorf("NewBuilder() error = nil, want an error")
+	}
+}
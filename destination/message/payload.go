@@ -0,0 +1,82 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"encoding/json"
+	"fmt"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// PayloadFormat defines which parts of a record make up a message payload.
+type PayloadFormat int
+
+const (
+	// PayloadFormatAfter takes a message payload from the data after the change.
+	PayloadFormatAfter PayloadFormat = iota
+	// PayloadFormatBefore takes a message payload from the data before the change.
+	PayloadFormatBefore
+	// PayloadFormatBoth encodes the data before and after the change into a JSON object.
+	PayloadFormatBoth
+	// PayloadFormatKeyOnlyOnDelete takes a message payload from the data after the change,
+	// or from the record key if the record is a delete.
+	PayloadFormatKeyOnlyOnDelete
+	// PayloadFormatOpenCDC encodes the whole record into an OpenCDC envelope.
+	PayloadFormatOpenCDC
+)
+
+// payloadFor returns a message payload of the record in the format.
+func (f PayloadFormat) payloadFor(record sdk.Record) ([]byte, error) {
+	switch f {
+	case PayloadFormatAfter:
+		return DataBytes(record.Payload.After), nil
+
+	case PayloadFormatBefore:
+		return DataBytes(record.Payload.Before), nil
+
+	case PayloadFormatBoth:
+		// the data is encoded the same way as in the OpenCDC envelope
+		payload, err := json.Marshal(record.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("marshal payload: %w", err)
+		}
+
+		return payload, nil
+
+	case PayloadFormatKeyOnlyOnDelete:
+		if record.Operation == sdk.OperationDelete {
+			return DataBytes(record.Key), nil
+		}
+
+		return DataBytes(record.Payload.After), nil
+
+	case PayloadFormatOpenCDC:
+		// the envelope is formatted according to the record format the destination is configured with
+		return record.Bytes(), nil
+
+	default:
+		return nil, fmt.Errorf("unknown payload format %d", f)
+	}
+}
+
+// DataBytes returns bytes of the data, or nil if there's no data, so that records without a payload don't panic.
+func DataBytes(data sdk.Data) []byte {
+	if data == nil {
+		return nil
+	}
+
+	return data.Bytes()
+}
@@ -0,0 +1,155 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"encoding/json"
+	"testing"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+func TestPayloadFormat_payloadFor(t *testing.T) {
+	t.Parallel()
+
+	update := sdk.Record{
+		Operation: sdk.OperationUpdate,
+		Key:       sdk.RawData("42"),
+		Payload: sdk.Change{
+			Before: sdk.RawData(`{"id":42,"status":"new"}`),
+			After:  sdk.StructuredData{"id": 42, "status": "paid"},
+		},
+	}
+
+	deletion := sdk.Record{
+		Operation: sdk.OperationDelete,
+		Key:       sdk.RawData("42"),
+		Payload: sdk.Change{
+			Before: sdk.RawData(`{"id":42,"status":"paid"}`),
+		},
+	}
+
+	tests := []struct {
+		name   string
+		format PayloadFormat
+		record sdk.Record
+		want   string
+	}{
+		{
+			name:   "after",
+			format: PayloadFormatAfter,
+			record: update,
+			want:   `{"id":42,"status":"paid"}`,
+		},
+		{
+			name:   "after, delete",
+			format: PayloadFormatAfter,
+			record: deletion,
+			want:   "",
+		},
+		{
+			name:   "before",
+			format: PayloadFormatBefore,
+			record: update,
+			want:   `{"id":42,"status":"new"}`,
+		},
+		{
+			name:   "both, delete",
+			format: PayloadFormatBoth,
+			record: sdk.Record{
+				Operation: sdk.OperationDelete,
+				Payload:   sdk.Change{Before: sdk.StructuredData{"id": 42}},
+			},
+			want: `{"before":{"id":42},"after":null}`,
+		},
+		{
+			name:   "key only on delete, update",
+			format: PayloadFormatKeyOnlyOnDelete,
+			record: update,
+			want:   `{"id":42,"status":"paid"}`,
+		},
+		{
+			name:   "key only on delete, delete",
+			format: PayloadFormatKeyOnlyOnDelete,
+			record: deletion,
+			want:   "42",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.format.payloadFor(tt.record)
+			if err != nil {
+				t.Fatalf("PayloadFormat.payloadFor() error = %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("PayloadFormat.payloadFor() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPayloadFormat_payloadFor_openCDC(t *testing.T) {
+	t.Parallel()
+
+	record := sdk.Record{
+		Operation: sdk.OperationDelete,
+		Key:       sdk.StructuredData{"id": 42},
+		Metadata:  sdk.Metadata{"table": "orders"},
+		Payload: sdk.Change{
+			Before: sdk.StructuredData{"id": 42, "status": "paid"},
+		},
+	}
+
+	got, err := PayloadFormatOpenCDC.payloadFor(record)
+	if err != nil {
+		t.Fatalf("PayloadFormat.payloadFor() error = %v", err)
+	}
+
+	var envelope struct {
+		Operation string            `json:"operation"`
+		Metadata  map[string]string `json:"metadata"`
+		Key       map[string]any    `json:"key"`
+		Payload   struct {
+			Before map[string]any `json:"before"`
+			After  map[string]any `json:"after"`
+		} `json:"payload"`
+	}
+
+	if err := json.Unmarshal(got, &envelope); err != nil {
+		t.Fatalf("unmarshal envelope %s: %v", got, err)
+	}
+
+	if envelope.Operation != "delete" {
+		t.Errorf("envelope operation = %v, want %v", envelope.Operation, "delete")
+	}
+
+	if envelope.Metadata["table"] != "orders" {
+		t.Errorf("envelope metadata = %v, want the table field", envelope.Metadata)
+	}
+
+	if envelope.Key["id"] != float64(42) {
+		t.Errorf("envelope key = %v, want %v", envelope.Key, record.Key)
+	}
+
+	if envelope.Payload.Before["status"] != "paid" || envelope.Payload.After != nil {
+		t.Errorf("envelope payload = %+v, want only the data before the change", envelope.Payload)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/destination/message"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/nats-io/nats.go"
+)
+
+// Writer implements an Object Store bucket writer.
+// It puts objects synchronously.
+type Writer struct {
+	conn        *nats.Conn
+	objectStore nats.ObjectStore
+}
+
+// WriterParams is an incoming params for the NewWriter function.
+type WriterParams struct {
+	Conn   *nats.Conn
+	Bucket string
+}
+
+// NewWriter creates new instance of the Writer.
+func NewWriter(params WriterParams) (*Writer, error) {
+	jetstream, err := params.Conn.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("get jetstream context: %w", err)
+	}
+
+	objectStore, err := jetstream.ObjectStore(params.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("get object store bucket %q: %w", params.Bucket, err)
+	}
+
+	return &Writer{
+		conn:        params.Conn,
+		objectStore: objectStore,
+	}, nil
+}
+
+// Write synchronously applies records to the bucket one by one,
+// it stops at the first record which fails and returns the number of records applied before it.
+func (w *Writer) Write(_ context.Context, records []sdk.Record) (int, error) {
+	for i, record := range records {
+		if err := w.write(record); err != nil {
+			return i, err
+		}
+	}
+
+	return len(records), nil
+}
+
+// write synchronously applies a record to the bucket.
+// Delete records delete the object named from the record key,
+// and all other records put the record payload as the object, a record without a payload puts an empty object.
+// Objects are split into chunks, so their size isn't limited by the server's max payload.
+func (w *Writer) write(record sdk.Record) error {
+	if record.Key == nil || len(record.Key.Bytes()) == 0 {
+		return errors.New("record key is empty")
+	}
+
+	name := string(record.Key.Bytes())
+
+	if record.Operation == sdk.OperationDelete {
+		err := w.objectStore.Delete(name)
+		if err != nil && !errors.Is(err, nats.ErrObjectNotFound) {
+			return fmt.Errorf("delete object %q: %w", name, err)
+		}
+
+		return nil
+	}
+
+	if _, err := w.objectStore.PutBytes(name, message.DataBytes(record.Payload.After)); err != nil {
+		return fmt.Errorf("put object %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// Close closes the underlying NATS connection.
+func (w *Writer) Close() error {
+	if w.conn != nil {
+		w.conn.Close()
+	}
+
+	return nil
+}
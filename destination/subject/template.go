@@ -0,0 +1,111 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subject
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// Template renders subjects of messages, or other values, from records.
+type Template struct {
+	template *template.Template
+}
+
+// templateData is the data a Template is executed with.
+type templateData struct {
+	// Key is the record key.
+	Key string
+	// Operation is the record operation, such as create or delete.
+	Operation string
+	// Metadata is the record metadata.
+	Metadata sdk.Metadata
+	// Payload holds fields of the record payload after the change,
+	// it's nil if the payload isn't structured.
+	Payload sdk.StructuredData
+}
+
+// NewTemplate parses a Go text/template.
+// Missing metadata and payload fields make rendering fail instead of producing empty tokens.
+func NewTemplate(text string) (*Template, error) {
+	tmpl, err := template.New("subject").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+
+	return &Template{template: tmpl}, nil
+}
+
+// Render renders a subject for the record.
+// The rendered subject must be a valid subject to publish to, so it cannot contain wildcards.
+func (t *Template) Render(record sdk.Record) (string, error) {
+	subject, err := t.Execute(record)
+	if err != nil {
+		return "", err
+	}
+
+	if err := validate(subject); err != nil {
+		return "", fmt.Errorf("invalid subject %q: %w", subject, err)
+	}
+
+	return subject, nil
+}
+
+// Execute renders the template for the record as is,
+// without checking that the result is a valid subject.
+func (t *Template) Execute(record sdk.Record) (string, error) {
+	data := templateData{
+		Operation: record.Operation.String(),
+		Metadata:  record.Metadata,
+	}
+
+	if record.Key != nil {
+		data.Key = string(record.Key.Bytes())
+	}
+
+	if payload, ok := record.Payload.After.(sdk.StructuredData); ok {
+		data.Payload = payload
+	}
+
+	var buf bytes.Buffer
+	if err := t.template.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("execute template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// validate checks that the subject can be published to.
+func validate(subject string) error {
+	if strings.ContainsAny(subject, " \t\r\n") {
+		return errors.New("subject cannot contain whitespaces")
+	}
+
+	for _, token := range strings.Split(subject, ".") {
+		switch token {
+		case "":
+			return errors.New("subject cannot contain empty tokens")
+		case "*", ">":
+			return errors.New("subject cannot contain wildcards")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subject
+
+import (
+	"testing"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+func TestTemplate_Render(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		template string
+		record   sdk.Record
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "success, metadata and operation",
+			template: "orders.{{.Metadata.region}}.{{.Operation}}",
+			record: sdk.Record{
+				Operation: sdk.OperationUpdate,
+				Metadata:  sdk.Metadata{"region": "eu"},
+			},
+			want:    "orders.eu.update",
+			wantErr: false,
+		},
+		{
+			name:     "success, key and structured payload",
+			template: "tenants.{{.Payload.tenant}}.{{.Key}}",
+			record: sdk.Record{
+				Operation: sdk.OperationCreate,
+				Key:       sdk.RawData("42"),
+				Payload: sdk.Change{
+					After: sdk.StructuredData{"tenant": "acme"},
+				},
+			},
+			want:    "tenants.acme.42",
+			wantErr: false,
+		},
+		{
+			name:     "fail, missing metadata field",
+			template: "orders.{{.Metadata.region}}",
+			record: sdk.Record{
+				Operation: sdk.OperationCreate,
+				Metadata:  sdk.Metadata{},
+			},
+			wantErr: true,
+		},
+		{
+			name:     "fail, raw payload",
+			template: "tenants.{{.Payload.tenant}}",
+			record: sdk.Record{
+				Operation: sdk.OperationCreate,
+				Payload: sdk.Change{
+					After: sdk.RawData(`{"tenant":"acme"}`),
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name:     "fail, wildcard token",
+			template: "orders.{{.Metadata.region}}",
+			record: sdk.Record{
+				Operation: sdk.OperationCreate,
+				Metadata:  sdk.Metadata{"region": "*"},
+			},
+			wantErr: true,
+		},
+		{
+			name:     "fail, full wildcard token",
+			template: "orders.{{.Key}}",
+			record: sdk.Record{
+				Operation: sdk.OperationCreate,
+				Key:       sdk.RawData(">"),
+			},
+			wantErr: true,
+		},
+		{
+			name:     "fail, empty token",
+			template: "orders.{{.Key}}.created",
+			record: sdk.Record{
+				Operation: sdk.OperationCreate,
+			},
+			wantErr: true,
+		},
+		{
+			name:     "fail, whitespace",
+			template: "orders.{{.Key}}",
+			record: sdk.Record{
+				Operation: sdk.OperationCreate,
+				Key:       sdk.RawData("new order"),
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tmpl, err := NewTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("NewTemplate() error = %v", err)
+			}
+
+			got, err := tmpl.Render(tt.record)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Template.Render() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("Template.Render() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTemplate_invalid(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewTemplate("orders.{{.Operation"); err == nil {
+		t.Errorf("NewTemplate() error = nil, want an error")
+	}
+}
@@ -17,10 +17,13 @@ package source
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"strconv"
 
 	"github.com/conduitio-labs/conduit-connector-nats-jetstream/config"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/source/jetstream"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/source/message"
 	"github.com/conduitio-labs/conduit-connector-nats-jetstream/validator"
 	"github.com/google/uuid"
 	"github.com/nats-io/nats.go"
@@ -39,9 +42,29 @@ const (
 	defaultDeliverPolicy = nats.DeliverAllPolicy
 	// defaultAckPolicy is the default message acknowledge policy.
 	defaultAckPolicy = nats.AckExplicitPolicy
+	// defaultConsumerLifecycle is the default consumer lifecycle.
+	defaultConsumerLifecycle = jetstream.ConsumerLifecycleDelete
+	// defaultConsumerType is the default JetStream consumer type.
+	defaultConsumerType = jetstream.ConsumerTypePush
+	// defaultBatchSize is the default number of messages a pull consumer fetches at once.
+	defaultBatchSize = 256
+	// defaultMaxWait is the default amount of time a pull consumer waits for a batch.
+	defaultMaxWait = time.Second * 5
+	// defaultHeaderPrefix is the default prefix of metadata keys that hold message headers.
+	defaultHeaderPrefix = "nats.header."
+	// defaultKeyHeader is the default name of a header that holds a record key.
+	defaultKeyHeader = nats.MsgIdHdr
+	// defaultObjectMaxContentSize is the default maximum size of an object whose contents are put into a record.
+	defaultObjectMaxContentSize = 1024 * 1024
+	// defaultKVKeys is the default pattern of keys watched in the Key-Value mode.
+	defaultKVKeys = ">"
 )
 
 const (
+	// ConfigKeyQueueGroup is a config name for a core NATS queue group.
+	ConfigKeyQueueGroup = "core.queueGroup"
+	// ConfigKeyObjectMaxContentSize is a config name for a max size of an object whose contents are put into a record.
+	ConfigKeyObjectMaxContentSize = "object.maxContentSize"
 	// ConfigKeyBufferSize is a config name for a buffer size.
 	ConfigKeyBufferSize = "bufferSize"
 	// ConfigKeyDeliverSubject is a config name for a deliver subject.
@@ -52,6 +75,40 @@ const (
 	ConfigKeyDeliverPolicy = "deliverPolicy"
 	// ConfigKeyAckPolicy is a config name for a message acknowledge policy.
 	ConfigKeyAckPolicy = "ackPolicy"
+	// ConfigKeyConsumerType is a config name for a consumer type.
+	ConfigKeyConsumerType = "consumerType"
+	// ConfigKeyBatchSize is a config name for a pull consumer batch size.
+	ConfigKeyBatchSize = "batchSize"
+	// ConfigKeyMaxWait is a config name for a pull consumer max wait duration.
+	ConfigKeyMaxWait = "maxWait"
+	// ConfigKeyHeaderPrefix is a config name for a metadata prefix of message headers.
+	ConfigKeyHeaderPrefix = "headerPrefix"
+	// ConfigKeyKeySource is a config name for a record key source.
+	ConfigKeyKeySource = "keySource"
+	// ConfigKeyStartSequence is a config name for a stream sequence to start receiving messages from.
+	ConfigKeyStartSequence = "startSequence"
+	// ConfigKeyStartTime is a config name for a time to start receiving messages from.
+	ConfigKeyStartTime = "startTime"
+	// ConfigKeyEndTime is a config name for a time to stop receiving messages at.
+	ConfigKeyEndTime = "endTime"
+	// ConfigKeyAckWait is a config name for an acknowledgement wait duration.
+	ConfigKeyAckWait = "ackWait"
+	// ConfigKeyMaxDeliver is a config name for a max number of delivery attempts.
+	ConfigKeyMaxDeliver = "maxDeliver"
+	// ConfigKeyBackOff is a config name for a list of redelivery backoff durations.
+	ConfigKeyBackOff = "backoff"
+	// ConfigKeyMaxAckPending is a config name for a max number of unacknowledged messages.
+	ConfigKeyMaxAckPending = "maxAckPending"
+	// ConfigKeyConsumerLifecycle is a config name for a consumer lifecycle.
+	ConfigKeyConsumerLifecycle = "consumerLifecycle"
+	// ConfigKeyStopAtTail is a config name for a flag which makes the connector stop at the stream tail.
+	ConfigKeyStopAtTail = "stopAtTail"
+	// ConfigKeyTailAction is a config name for an action taken at the stream tail.
+	ConfigKeyTailAction = "tailAction"
+	// ConfigKeyPositionOverride is a config name for a flag which allows discarding a mismatched position.
+	ConfigKeyPositionOverride = "positionOverride"
+	// ConfigKeyPurgedPositionPolicy is a config name for an action taken if the resume sequence was purged.
+	ConfigKeyPurgedPositionPolicy = "purgedPositionPolicy"
 )
 
 // Config holds source specific configurable values.
@@ -65,9 +122,51 @@ type Config struct {
 	// DeliverSubject specifies the JetStream consumer deliver subject.
 	DeliverSubject string `json:"deliverSubject" validate:"required"`
 	// DeliverPolicy defines where in the stream the connector should start receiving messages.
-	DeliverPolicy nats.DeliverPolicy `key:"deliverPolicy" validate:"oneof=0 2"`
+	DeliverPolicy nats.DeliverPolicy `key:"deliverPolicy" validate:"oneof=0 1 2 3 4 5"`
+	// StartSequence is a stream sequence to start receiving messages from,
+	// used with the DeliverByStartSequencePolicy.
+	StartSequence uint64 `key:"startSequence" validate:"required_if=DeliverPolicy 3"`
+	// StartTime is a time to start receiving messages from,
+	// used with the DeliverByStartTimePolicy.
+	StartTime time.Time `key:"startTime" validate:"required_if=DeliverPolicy 4"`
+	// EndTime makes the connector stop before the first message created after it, used in the JetStream mode.
+	EndTime time.Time `key:"endTime"`
 	// AckPolicy defines how messages should be acknowledged.
 	AckPolicy nats.AckPolicy `key:"ackPolicy" validate:"oneof=0 1 2"`
+	// AckWait is how long the server waits for an acknowledgement before redelivering a message.
+	AckWait time.Duration `key:"ackWait" validate:"min=0"`
+	// MaxDeliver is the maximum number of delivery attempts of a message, -1 means unlimited.
+	MaxDeliver int `key:"maxDeliver" validate:"min=-1"`
+	// BackOff is a list of durations the server waits before each redelivery of a message.
+	BackOff []time.Duration `key:"backoff"`
+	// MaxAckPending is the maximum number of unacknowledged messages, -1 means unlimited.
+	MaxAckPending int `key:"maxAckPending" validate:"min=-1"`
+	// ConsumerLifecycle defines what happens with the durable consumer on teardown.
+	ConsumerLifecycle jetstream.ConsumerLifecycle `key:"consumerLifecycle" validate:"oneof=0 1 2"`
+	// ConsumerType defines whether the connector uses a push or a pull consumer.
+	ConsumerType jetstream.ConsumerType `key:"consumerType" validate:"oneof=0 1"`
+	// BatchSize is the maximum number of messages a pull consumer fetches at once.
+	BatchSize int `key:"batchSize" validate:"omitempty,min=1"`
+	// MaxWait is the maximum amount of time a pull consumer waits for a batch of messages.
+	MaxWait time.Duration `key:"maxWait"`
+	// HeaderPrefix is prepended to the names of message headers copied into a record's metadata.
+	HeaderPrefix string `key:"headerPrefix"`
+	// KeySource defines where the connector takes record keys from.
+	KeySource message.KeySource `key:"keySource"`
+	// ObjectMaxContentSize is the maximum size of an object whose contents are put into a record,
+	// records of larger objects contain references to them.
+	ObjectMaxContentSize uint64 `key:"object.maxContentSize"`
+	// QueueGroup is a name of a queue group the connector subscribes in, used in the core NATS mode.
+	QueueGroup string `key:"core.queueGroup"`
+	// StopAtTail makes the connector stop at the last message of the subject
+	// which was in the stream when the connector started, used in the JetStream mode.
+	StopAtTail bool `key:"stopAtTail"`
+	// TailAction defines what the connector does once it reaches the stream tail.
+	TailAction jetstream.TailAction `key:"tailAction" validate:"oneof=0 1"`
+	// PositionOverride makes the connector discard a position which belongs to a different stream or subject.
+	PositionOverride bool `key:"positionOverride"`
+	// PurgedPositionPolicy defines what the connector does if messages following the position were removed.
+	PurgedPositionPolicy jetstream.PurgedPolicy `key:"purgedPositionPolicy" validate:"oneof=0 1 2"`
 }
 
 // Parse maps the incoming map to the Config and validates it.
@@ -81,6 +180,8 @@ func Parse(cfg map[string]string) (Config, error) {
 		Config:         common,
 		DeliverSubject: cfg[ConfigKeyDeliverSubject],
 		Durable:        cfg[ConfigKeyDurable],
+		HeaderPrefix:   cfg[ConfigKeyHeaderPrefix],
+		QueueGroup:     cfg[ConfigKeyQueueGroup],
 	}
 
 	if err := sourceConfig.parseBufferSize(cfg[ConfigKeyBufferSize]); err != nil {
@@ -91,19 +192,82 @@ func Parse(cfg map[string]string) (Config, error) {
 		return Config{}, fmt.Errorf("parse deliver policy: %w", err)
 	}
 
+	if err := sourceConfig.parseStartSequence(cfg[ConfigKeyStartSequence]); err != nil {
+		return Config{}, fmt.Errorf("parse start sequence: %w", err)
+	}
+
+	if err := sourceConfig.parseReplay(cfg); err != nil {
+		return Config{}, fmt.Errorf("parse replay: %w", err)
+	}
+
 	if err := sourceConfig.parseAckPolicy(cfg[ConfigKeyAckPolicy]); err != nil {
 		return Config{}, fmt.Errorf("parse ack policy: %w", err)
 	}
 
+	if err := sourceConfig.parseRedelivery(cfg); err != nil {
+		return Config{}, fmt.Errorf("parse redelivery: %w", err)
+	}
+
+	if err := sourceConfig.parseConsumerLifecycle(cfg[ConfigKeyConsumerLifecycle]); err != nil {
+		return Config{}, fmt.Errorf("parse consumer lifecycle: %w", err)
+	}
+
+	if err := sourceConfig.parsePull(cfg); err != nil {
+		return Config{}, fmt.Errorf("parse pull: %w", err)
+	}
+
+	if err := sourceConfig.parseKeySource(cfg[ConfigKeyKeySource]); err != nil {
+		return Config{}, fmt.Errorf("parse key source: %w", err)
+	}
+
+	if err := sourceConfig.parseObjectMaxContentSize(cfg[ConfigKeyObjectMaxContentSize]); err != nil {
+		return Config{}, fmt.Errorf("parse object max content size: %w", err)
+	}
+
+	if err := sourceConfig.parsePosition(cfg); err != nil {
+		return Config{}, fmt.Errorf("parse position: %w", err)
+	}
+
+	if err := sourceConfig.parseStopAtTail(cfg); err != nil {
+		return Config{}, fmt.Errorf("parse stop at tail: %w", err)
+	}
+
 	sourceConfig.setDefaults()
 
 	if err := validator.Validate(&sourceConfig); err != nil {
 		return Config{}, fmt.Errorf("validate source config: %w", err)
 	}
 
+	if err := sourceConfig.validateSubject(); err != nil {
+		return Config{}, fmt.Errorf("validate subject: %w", err)
+	}
+
+	if err := sourceConfig.validatePullConsumer(); err != nil {
+		return Config{}, fmt.Errorf("validate pull consumer: %w", err)
+	}
+
+	if err := sourceConfig.validateBackOff(); err != nil {
+		return Config{}, fmt.Errorf("validate backoff: %w", err)
+	}
+
 	return sourceConfig, nil
 }
 
+// parseObjectMaxContentSize parses the objectMaxContentSize string and
+// if it's not empty set cfg.ObjectMaxContentSize to its unsigned integer representation.
+func (c *Config) parseObjectMaxContentSize(maxContentSizeStr string) error {
+	if maxContentSizeStr != "" {
+		maxContentSize, err := strconv.ParseUint(maxContentSizeStr, 10, 64)
+		if err != nil {
+			return fmt.Errorf("\"%s\" must be a positive integer", ConfigKeyObjectMaxContentSize)
+		}
+
+		c.ObjectMaxContentSize = maxContentSize
+	}
+
+	return nil
+}
+
 // parseBufferSize parses the bufferSize string and
 // if it's not empty set cfg.BufferSize to its integer representation.
 func (c *Config) parseBufferSize(bufferSizeStr string) error {
@@ -126,6 +290,14 @@ func (c *Config) parseDeliverPolicy(deliverPolicyStr string) error {
 		c.DeliverPolicy = nats.DeliverAllPolicy
 	case "new":
 		c.DeliverPolicy = nats.DeliverNewPolicy
+	case "last":
+		c.DeliverPolicy = nats.DeliverLastPolicy
+	case "last_per_subject":
+		c.DeliverPolicy = nats.DeliverLastPerSubjectPolicy
+	case "by_start_sequence":
+		c.DeliverPolicy = nats.DeliverByStartSequencePolicy
+	case "by_start_time":
+		c.DeliverPolicy = nats.DeliverByStartTimePolicy
 	default:
 		return fmt.Errorf("invalid deliver policy %q", deliverPolicyStr)
 	}
@@ -133,6 +305,74 @@ func (c *Config) parseDeliverPolicy(deliverPolicyStr string) error {
 	return nil
 }
 
+// parseStartSequence parses the startSequence string and
+// if it's not empty set cfg.StartSequence to its unsigned integer representation.
+func (c *Config) parseStartSequence(startSequenceStr string) error {
+	if startSequenceStr != "" {
+		startSequence, err := strconv.ParseUint(startSequenceStr, 10, 64)
+		if err != nil {
+			return fmt.Errorf("\"%s\" must be a positive integer", ConfigKeyStartSequence)
+		}
+
+		c.StartSequence = startSequence
+	}
+
+	return nil
+}
+
+// parseReplay parses the startTime and endTime strings, which define a time range of replayed messages,
+// and validates them. It must be called after the deliver policy is parsed.
+func (c *Config) parseReplay(cfg map[string]string) error {
+	if err := c.parseStartTime(cfg[ConfigKeyStartTime]); err != nil {
+		return fmt.Errorf("parse start time: %w", err)
+	}
+
+	// the start time alone makes the connector replay messages starting from it
+	if cfg[ConfigKeyDeliverPolicy] == "" && !c.StartTime.IsZero() {
+		c.DeliverPolicy = nats.DeliverByStartTimePolicy
+	}
+
+	if err := c.parseEndTime(cfg[ConfigKeyEndTime]); err != nil {
+		return fmt.Errorf("parse end time: %w", err)
+	}
+
+	if err := c.validateEndTime(); err != nil {
+		return fmt.Errorf("validate end time: %w", err)
+	}
+
+	return nil
+}
+
+// parseStartTime parses the startTime string and
+// if it's not empty set cfg.StartTime to its time.Time representation.
+func (c *Config) parseStartTime(startTimeStr string) error {
+	if startTimeStr != "" {
+		startTime, err := time.Parse(time.RFC3339, startTimeStr)
+		if err != nil {
+			return fmt.Errorf("\"%s\" must be a valid RFC3339 time", ConfigKeyStartTime)
+		}
+
+		c.StartTime = startTime
+	}
+
+	return nil
+}
+
+// parseEndTime parses the endTime string and
+// if it's not empty set cfg.EndTime to its time.Time representation.
+func (c *Config) parseEndTime(endTimeStr string) error {
+	if endTimeStr != "" {
+		endTime, err := time.Parse(time.RFC3339, endTimeStr)
+		if err != nil {
+			return fmt.Errorf("\"%s\" must be a valid RFC3339 time", ConfigKeyEndTime)
+		}
+
+		c.EndTime = endTime
+	}
+
+	return nil
+}
+
 // parseAckPolicy parses and converts the ackPolicy string into nats.AckPolicy.
 func (c *Config) parseAckPolicy(ackPolicyStr string) error {
 	switch strings.ToLower(ackPolicyStr) {
@@ -149,12 +389,349 @@ func (c *Config) parseAckPolicy(ackPolicyStr string) error {
 	return nil
 }
 
+// parseRedelivery parses the ackWait, maxDeliver, backoff and maxAckPending strings
+// and if they're not empty sets their values to the corresponding fields.
+func (c *Config) parseRedelivery(cfg map[string]string) error {
+	if cfg[ConfigKeyAckWait] != "" {
+		ackWait, err := time.ParseDuration(cfg[ConfigKeyAckWait])
+		if err != nil {
+			return fmt.Errorf("\"%s\" must be a valid duration", ConfigKeyAckWait)
+		}
+
+		c.AckWait = ackWait
+	}
+
+	if cfg[ConfigKeyMaxDeliver] != "" {
+		maxDeliver, err := strconv.Atoi(cfg[ConfigKeyMaxDeliver])
+		if err != nil {
+			return fmt.Errorf("\"%s\" must be an integer", ConfigKeyMaxDeliver)
+		}
+
+		c.MaxDeliver = maxDeliver
+	}
+
+	if cfg[ConfigKeyBackOff] != "" {
+		for _, backOffStr := range strings.Split(cfg[ConfigKeyBackOff], ",") {
+			backOff, err := time.ParseDuration(strings.TrimSpace(backOffStr))
+			if err != nil || backOff <= 0 {
+				return fmt.Errorf("\"%s\" must be a comma-separated list of positive durations", ConfigKeyBackOff)
+			}
+
+			c.BackOff = append(c.BackOff, backOff)
+		}
+	}
+
+	if cfg[ConfigKeyMaxAckPending] != "" {
+		maxAckPending, err := strconv.Atoi(cfg[ConfigKeyMaxAckPending])
+		if err != nil {
+			return fmt.Errorf("\"%s\" must be an integer", ConfigKeyMaxAckPending)
+		}
+
+		c.MaxAckPending = maxAckPending
+	}
+
+	return nil
+}
+
+// parseConsumerLifecycle parses and converts the consumerLifecycle string into jetstream.ConsumerLifecycle.
+func (c *Config) parseConsumerLifecycle(consumerLifecycleStr string) error {
+	switch strings.ToLower(consumerLifecycleStr) {
+	case "delete", "":
+		c.ConsumerLifecycle = jetstream.ConsumerLifecycleDelete
+	case "retain":
+		c.ConsumerLifecycle = jetstream.ConsumerLifecycleRetain
+	case "drain":
+		c.ConsumerLifecycle = jetstream.ConsumerLifecycleDrain
+	default:
+		return fmt.Errorf("invalid consumer lifecycle %q", consumerLifecycleStr)
+	}
+
+	// a kept consumer is reused by its name, so a generated one would leave it behind on each restart
+	if c.ConsumerLifecycle != jetstream.ConsumerLifecycleDelete && c.Durable == "" {
+		return fmt.Errorf("consumer lifecycle %q requires %q to be set", consumerLifecycleStr, ConfigKeyDurable)
+	}
+
+	return nil
+}
+
+// parseConsumerType parses and converts the consumerType string into jetstream.ConsumerType.
+func (c *Config) parseConsumerType(consumerTypeStr string) error {
+	switch strings.ToLower(consumerTypeStr) {
+	case "push", "":
+		c.ConsumerType = jetstream.ConsumerTypePush
+	case "pull":
+		c.ConsumerType = jetstream.ConsumerTypePull
+	default:
+		return fmt.Errorf("invalid consumer type %q", consumerTypeStr)
+	}
+
+	return nil
+}
+
+// parsePosition parses the strings which define how the connector handles the position it resumes from.
+func (c *Config) parsePosition(cfg map[string]string) error {
+	if err := c.parsePositionOverride(cfg[ConfigKeyPositionOverride]); err != nil {
+		return fmt.Errorf("parse position override: %w", err)
+	}
+
+	if err := c.parsePurgedPositionPolicy(cfg[ConfigKeyPurgedPositionPolicy]); err != nil {
+		return fmt.Errorf("parse purged position policy: %w", err)
+	}
+
+	return nil
+}
+
+// parsePositionOverride parses the positionOverride string and
+// if it's not empty set cfg.PositionOverride to its boolean representation.
+func (c *Config) parsePositionOverride(positionOverrideStr string) error {
+	if positionOverrideStr != "" {
+		positionOverride, err := strconv.ParseBool(positionOverrideStr)
+		if err != nil {
+			return fmt.Errorf("\"%s\" must be a boolean", ConfigKeyPositionOverride)
+		}
+
+		c.PositionOverride = positionOverride
+	}
+
+	return nil
+}
+
+// parsePurgedPositionPolicy parses the purgedPositionPolicy string
+// and sets cfg.PurgedPositionPolicy to its jetstream.PurgedPolicy representation.
+func (c *Config) parsePurgedPositionPolicy(purgedPositionPolicyStr string) error {
+	switch strings.ToLower(purgedPositionPolicyStr) {
+	case "fail", "":
+		c.PurgedPositionPolicy = jetstream.PurgedPolicyFail
+	case "first_available":
+		c.PurgedPositionPolicy = jetstream.PurgedPolicyFirstAvailable
+	case "by_timestamp":
+		c.PurgedPositionPolicy = jetstream.PurgedPolicyByTimestamp
+	default:
+		return fmt.Errorf("invalid purged position policy %q", purgedPositionPolicyStr)
+	}
+
+	return nil
+}
+
+// parseStopAtTail parses the stopAtTail and tailAction strings.
+// The stopAtTail can be set in the JetStream mode only, as other modes have no stream tail.
+func (c *Config) parseStopAtTail(cfg map[string]string) error {
+	if cfg[ConfigKeyStopAtTail] != "" {
+		stopAtTail, err := strconv.ParseBool(cfg[ConfigKeyStopAtTail])
+		if err != nil {
+			return fmt.Errorf("\"%s\" must be a boolean", ConfigKeyStopAtTail)
+		}
+
+		if stopAtTail && c.Mode != config.ModeJetStream {
+			return fmt.Errorf("\"%s\" can be set in the jetstream mode only", ConfigKeyStopAtTail)
+		}
+
+		c.StopAtTail = stopAtTail
+	}
+
+	switch strings.ToLower(cfg[ConfigKeyTailAction]) {
+	case "stop", "":
+		c.TailAction = jetstream.TailActionStop
+	case "idle":
+		c.TailAction = jetstream.TailActionIdle
+	default:
+		return fmt.Errorf("invalid tail action %q", cfg[ConfigKeyTailAction])
+	}
+
+	return nil
+}
+
+// parsePull parses the consumerType, batchSize and maxWait strings, which configure a pull consumer.
+func (c *Config) parsePull(cfg map[string]string) error {
+	if err := c.parseConsumerType(cfg[ConfigKeyConsumerType]); err != nil {
+		return fmt.Errorf("parse consumer type: %w", err)
+	}
+
+	if err := c.parseBatchSize(cfg[ConfigKeyBatchSize]); err != nil {
+		return fmt.Errorf("parse batch size: %w", err)
+	}
+
+	if err := c.parseMaxWait(cfg[ConfigKeyMaxWait]); err != nil {
+		return fmt.Errorf("parse max wait: %w", err)
+	}
+
+	return nil
+}
+
+// parseBatchSize parses the batchSize string and
+// if it's not empty set cfg.BatchSize to its integer representation.
+func (c *Config) parseBatchSize(batchSizeStr string) error {
+	if batchSizeStr != "" {
+		batchSize, err := strconv.Atoi(batchSizeStr)
+		if err != nil {
+			return fmt.Errorf("\"%s\" must be an integer", ConfigKeyBatchSize)
+		}
+
+		c.BatchSize = batchSize
+	}
+
+	return nil
+}
+
+// parseMaxWait parses the maxWait string and
+// if it's not empty set cfg.MaxWait to its time.Duration representation.
+func (c *Config) parseMaxWait(maxWaitStr string) error {
+	if maxWaitStr != "" {
+		maxWait, err := time.ParseDuration(maxWaitStr)
+		if err != nil {
+			return fmt.Errorf("\"%s\" must be a valid duration", ConfigKeyMaxWait)
+		}
+
+		c.MaxWait = maxWait
+	}
+
+	return nil
+}
+
+// parseKeySource parses the keySource string and converts it into message.KeySource.
+// The string must be in the format <type>:<value>, where the type is one of:
+//   - header - the value is a header name;
+//   - subject - the value is a zero-based index of a subject token;
+//   - payload - the value is a dot-separated path to a field of a JSON payload.
+func (c *Config) parseKeySource(keySourceStr string) error {
+	if keySourceStr == "" {
+		c.KeySource = message.KeySource{
+			Type:   message.KeySourceHeader,
+			Header: defaultKeyHeader,
+		}
+
+		return nil
+	}
+
+	keySourceType, value, found := strings.Cut(keySourceStr, ":")
+	if !found || value == "" {
+		return fmt.Errorf("\"%s\" must be in the format <type>:<value>", ConfigKeyKeySource)
+	}
+
+	switch strings.ToLower(keySourceType) {
+	case "header":
+		c.KeySource = message.KeySource{
+			Type:   message.KeySourceHeader,
+			Header: value,
+		}
+
+	case "subject":
+		token, err := strconv.Atoi(value)
+		if err != nil || token < 0 {
+			return fmt.Errorf("\"%s\" subject token must be a non-negative integer", ConfigKeyKeySource)
+		}
+
+		c.KeySource = message.KeySource{
+			Type:         message.KeySourceSubject,
+			SubjectToken: token,
+		}
+
+	case "payload":
+		c.KeySource = message.KeySource{
+			Type:        message.KeySourcePayload,
+			PayloadPath: strings.Split(value, "."),
+		}
+
+	default:
+		return fmt.Errorf("invalid key source type %q", keySourceType)
+	}
+
+	return nil
+}
+
+// validateSubject checks that the subject is set in the modes which subscribe to it,
+// as the subject template is used by the destination only.
+func (c *Config) validateSubject() error {
+	if c.Mode != config.ModeJetStream && c.Mode != config.ModeCore {
+		return nil
+	}
+
+	if c.Subject == "" {
+		return fmt.Errorf("%q value must be set", config.KeySubject)
+	}
+
+	return nil
+}
+
+// validatePullConsumer checks that pull consumer specific fields are consistent.
+func (c *Config) validatePullConsumer() error {
+	if c.ConsumerType != jetstream.ConsumerTypePull {
+		return nil
+	}
+
+	// JetStream doesn't allow pull consumers without acknowledgements
+	if c.AckPolicy == nats.AckNonePolicy {
+		return fmt.Errorf("\"%s\" cannot be \"none\" for a pull consumer", ConfigKeyAckPolicy)
+	}
+
+	if c.MaxWait <= 0 {
+		return fmt.Errorf("\"%s\" must be positive for a pull consumer", ConfigKeyMaxWait)
+	}
+
+	return nil
+}
+
+// validateEndTime checks that the end time is set in the JetStream mode only and is after the start time.
+func (c *Config) validateEndTime() error {
+	if c.EndTime.IsZero() {
+		return nil
+	}
+
+	if c.Mode != config.ModeJetStream {
+		return fmt.Errorf("\"%s\" can be set in the jetstream mode only", ConfigKeyEndTime)
+	}
+
+	if c.DeliverPolicy == nats.DeliverByStartTimePolicy && !c.EndTime.After(c.StartTime) {
+		return fmt.Errorf("\"%s\" must be after \"%s\"", ConfigKeyEndTime, ConfigKeyStartTime)
+	}
+
+	return nil
+}
+
+// validateBackOff checks that the backoff is consistent with the ackWait and the maxDeliver.
+func (c *Config) validateBackOff() error {
+	if len(c.BackOff) == 0 {
+		return nil
+	}
+
+	// the server uses the first backoff duration as the ack wait
+	if c.AckWait != 0 {
+		return fmt.Errorf("\"%s\" and \"%s\" cannot be set together", ConfigKeyAckWait, ConfigKeyBackOff)
+	}
+
+	if c.MaxDeliver <= len(c.BackOff) {
+		return fmt.Errorf("\"%s\" must be greater than the number of \"%s\" durations", ConfigKeyMaxDeliver, ConfigKeyBackOff)
+	}
+
+	return nil
+}
+
 // setDefaults set default values for empty fields.
 func (c *Config) setDefaults() {
+	// in the Key-Value mode the subject is a pattern of watched keys
+	if c.Mode == config.ModeKV && c.Subject == "" {
+		c.Subject = defaultKVKeys
+	}
+
+	if c.Mode == config.ModeObject && c.ObjectMaxContentSize == 0 {
+		c.ObjectMaxContentSize = defaultObjectMaxContentSize
+	}
+
 	if c.BufferSize == 0 {
 		c.BufferSize = defaultBufferSize
 	}
 
+	// batch size and max wait are used by pull consumers only
+	if c.ConsumerType == jetstream.ConsumerTypePull {
+		if c.BatchSize == 0 {
+			c.BatchSize = defaultBatchSize
+		}
+
+		if c.MaxWait == 0 {
+			c.MaxWait = defaultMaxWait
+		}
+	}
+
 	if c.Durable == "" {
 		c.Durable = c.generateDurableName()
 	}
@@ -162,6 +739,10 @@ func (c *Config) setDefaults() {
 	if c.DeliverSubject == "" {
 		c.DeliverSubject = c.generateDeliverSubject()
 	}
+
+	if c.HeaderPrefix == "" {
+		c.HeaderPrefix = defaultHeaderPrefix
+	}
 }
 
 // generateDurableName generates a random durable (consumer) name.
@@ -18,11 +18,20 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/conduitio-labs/conduit-connector-nats-jetstream/config"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/source/jetstream"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/source/message"
 	"github.com/nats-io/nats.go"
 )
 
+// defaultKeySource is a key source the connector uses if the keySource is not set.
+var defaultKeySource = message.KeySource{
+	Type:   message.KeySourceHeader,
+	Header: defaultKeyHeader,
+}
+
 func TestParse(t *testing.T) {
 	t.Parallel()
 
@@ -54,6 +63,8 @@ func TestParse(t *testing.T) {
 				},
 				DeliverSubject: "super.subject",
 				BufferSize:     defaultBufferSize,
+				HeaderPrefix:   defaultHeaderPrefix,
+				KeySource:      defaultKeySource,
 				DeliverPolicy:  defaultDeliverPolicy,
 				AckPolicy:      defaultAckPolicy,
 			},
@@ -76,6 +87,8 @@ func TestParse(t *testing.T) {
 				},
 				DeliverSubject: "",
 				BufferSize:     defaultBufferSize,
+				HeaderPrefix:   defaultHeaderPrefix,
+				KeySource:      defaultKeySource,
 				DeliverPolicy:  defaultDeliverPolicy,
 				AckPolicy:      defaultAckPolicy,
 			},
@@ -98,6 +111,8 @@ func TestParse(t *testing.T) {
 					ReconnectWait: config.DefaultReconnectWait,
 				},
 				BufferSize:    128,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     defaultKeySource,
 				DeliverPolicy: defaultDeliverPolicy,
 				AckPolicy:     defaultAckPolicy,
 			},
@@ -119,6 +134,8 @@ func TestParse(t *testing.T) {
 					ReconnectWait: config.DefaultReconnectWait,
 				},
 				BufferSize:    defaultBufferSize,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     defaultKeySource,
 				DeliverPolicy: defaultDeliverPolicy,
 				AckPolicy:     defaultAckPolicy,
 			},
@@ -164,8 +181,10 @@ func TestParse(t *testing.T) {
 					MaxReconnects: config.DefaultMaxReconnects,
 					ReconnectWait: config.DefaultReconnectWait,
 				},
-				BufferSize: defaultBufferSize,
-				AckPolicy:  nats.AckAllPolicy,
+				BufferSize:   defaultBufferSize,
+				HeaderPrefix: defaultHeaderPrefix,
+				KeySource:    defaultKeySource,
+				AckPolicy:    nats.AckAllPolicy,
 			},
 			wantErr: false,
 		},
@@ -186,6 +205,8 @@ func TestParse(t *testing.T) {
 					ReconnectWait: config.DefaultReconnectWait,
 				},
 				BufferSize:    defaultBufferSize,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     defaultKeySource,
 				DeliverPolicy: defaultDeliverPolicy,
 				AckPolicy:     nats.AckNonePolicy,
 			},
@@ -221,6 +242,8 @@ func TestParse(t *testing.T) {
 					ReconnectWait: config.DefaultReconnectWait,
 				},
 				BufferSize:    defaultBufferSize,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     defaultKeySource,
 				DeliverPolicy: nats.DeliverNewPolicy,
 				AckPolicy:     nats.AckExplicitPolicy,
 			},
@@ -258,11 +281,998 @@ func TestParse(t *testing.T) {
 				DeliverSubject: "my_super_durable.conduit",
 				Durable:        "my_super_durable",
 				BufferSize:     defaultBufferSize,
+				HeaderPrefix:   defaultHeaderPrefix,
+				KeySource:      defaultKeySource,
 				DeliverPolicy:  nats.DeliverAllPolicy,
 				AckPolicy:      nats.AckExplicitPolicy,
 			},
 			wantErr: false,
 		},
+		{
+			name: "success, pull consumer with default batch size and max wait",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:        "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:     "foo",
+					ConfigKeyConsumerType: "pull",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:    defaultBufferSize,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     defaultKeySource,
+				DeliverPolicy: defaultDeliverPolicy,
+				AckPolicy:     defaultAckPolicy,
+				ConsumerType:  jetstream.ConsumerTypePull,
+				BatchSize:     defaultBatchSize,
+				MaxWait:       defaultMaxWait,
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, pull consumer with custom batch size and max wait",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:        "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:     "foo",
+					ConfigKeyConsumerType: "pull",
+					ConfigKeyBatchSize:    "10",
+					ConfigKeyMaxWait:      "1s",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:    defaultBufferSize,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     defaultKeySource,
+				DeliverPolicy: defaultDeliverPolicy,
+				AckPolicy:     defaultAckPolicy,
+				ConsumerType:  jetstream.ConsumerTypePull,
+				BatchSize:     10,
+				MaxWait:       time.Second,
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, push consumer by default",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:        "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:     "foo",
+					ConfigKeyConsumerType: "",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:    defaultBufferSize,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     defaultKeySource,
+				DeliverPolicy: defaultDeliverPolicy,
+				AckPolicy:     defaultAckPolicy,
+				ConsumerType:  defaultConsumerType,
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail, invalid consumer type",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:        "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:     "foo",
+					ConfigKeyConsumerType: "wrong",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, invalid batch size",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:        "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:     "foo",
+					ConfigKeyConsumerType: "pull",
+					ConfigKeyBatchSize:    "-1",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, pull consumer with none ack policy",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:        "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:     "foo",
+					ConfigKeyConsumerType: "pull",
+					ConfigKeyAckPolicy:    "none",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "success, custom header prefix",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:        "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:     "foo",
+					ConfigKeyHeaderPrefix: "header.",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:    defaultBufferSize,
+				DeliverPolicy: defaultDeliverPolicy,
+				AckPolicy:     defaultAckPolicy,
+				HeaderPrefix:  "header.",
+				KeySource:     defaultKeySource,
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, header key source",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:     "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:  "foo",
+					ConfigKeyKeySource: "header:X-Key",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:    defaultBufferSize,
+				DeliverPolicy: defaultDeliverPolicy,
+				AckPolicy:     defaultAckPolicy,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     message.KeySource{Type: message.KeySourceHeader, Header: "X-Key"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, subject key source",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:     "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:  "foo",
+					ConfigKeyKeySource: "subject:2",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:    defaultBufferSize,
+				DeliverPolicy: defaultDeliverPolicy,
+				AckPolicy:     defaultAckPolicy,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     message.KeySource{Type: message.KeySourceSubject, SubjectToken: 2},
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, payload key source",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:     "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:  "foo",
+					ConfigKeyKeySource: "payload:user.id",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:    defaultBufferSize,
+				DeliverPolicy: defaultDeliverPolicy,
+				AckPolicy:     defaultAckPolicy,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource: message.KeySource{
+					Type:        message.KeySourcePayload,
+					PayloadPath: []string{"user", "id"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail, key source without a value",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:     "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:  "foo",
+					ConfigKeyKeySource: "header:",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, invalid key source subject token",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:     "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:  "foo",
+					ConfigKeyKeySource: "subject:-1",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, invalid key source type",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:     "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:  "foo",
+					ConfigKeyKeySource: "wrong:id",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "success, deliver policy last",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:         "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:      "foo",
+					ConfigKeyDeliverPolicy: "last",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:    defaultBufferSize,
+				AckPolicy:     defaultAckPolicy,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     defaultKeySource,
+				DeliverPolicy: nats.DeliverLastPolicy,
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, deliver policy last per subject",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:         "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:      "foo",
+					ConfigKeyDeliverPolicy: "last_per_subject",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:    defaultBufferSize,
+				AckPolicy:     defaultAckPolicy,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     defaultKeySource,
+				DeliverPolicy: nats.DeliverLastPerSubjectPolicy,
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, deliver policy by start sequence",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:         "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:      "foo",
+					ConfigKeyDeliverPolicy: "by_start_sequence",
+					ConfigKeyStartSequence: "42",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:    defaultBufferSize,
+				AckPolicy:     defaultAckPolicy,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     defaultKeySource,
+				DeliverPolicy: nats.DeliverByStartSequencePolicy,
+				StartSequence: 42,
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, deliver policy by start time",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:         "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:      "foo",
+					ConfigKeyDeliverPolicy: "by_start_time",
+					ConfigKeyStartTime:     "2022-08-30T14:00:00Z",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:    defaultBufferSize,
+				AckPolicy:     defaultAckPolicy,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     defaultKeySource,
+				DeliverPolicy: nats.DeliverByStartTimePolicy,
+				StartTime:     time.Date(2022, 8, 30, 14, 0, 0, 0, time.UTC),
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, time range without deliver policy",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:     "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:  "orders.>",
+					ConfigKeyStartTime: "2022-08-30T14:00:00Z",
+					ConfigKeyEndTime:   "2022-08-30T15:30:00Z",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "orders.>",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:    defaultBufferSize,
+				AckPolicy:     defaultAckPolicy,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     defaultKeySource,
+				DeliverPolicy: nats.DeliverByStartTimePolicy,
+				StartTime:     time.Date(2022, 8, 30, 14, 0, 0, 0, time.UTC),
+				EndTime:       time.Date(2022, 8, 30, 15, 30, 0, 0, time.UTC),
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail, end time before start time",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:     "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:  "orders.>",
+					ConfigKeyStartTime: "2022-08-30T15:30:00Z",
+					ConfigKeyEndTime:   "2022-08-30T14:00:00Z",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, invalid end time",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:    "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject: "orders.>",
+					ConfigKeyEndTime:  "today",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, deliver policy by start sequence without start sequence",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:         "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:      "foo",
+					ConfigKeyDeliverPolicy: "by_start_sequence",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, deliver policy by start time without start time",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:         "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:      "foo",
+					ConfigKeyDeliverPolicy: "by_start_time",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, invalid start sequence",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:         "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:      "foo",
+					ConfigKeyDeliverPolicy: "by_start_sequence",
+					ConfigKeyStartSequence: "-1",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, invalid start time",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:         "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:      "foo",
+					ConfigKeyDeliverPolicy: "by_start_time",
+					ConfigKeyStartTime:     "yesterday",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "success, custom ack wait, max deliver and max ack pending",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:         "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:      "foo",
+					ConfigKeyAckWait:       "1m",
+					ConfigKeyMaxDeliver:    "10",
+					ConfigKeyMaxAckPending: "-1",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:    defaultBufferSize,
+				DeliverPolicy: defaultDeliverPolicy,
+				AckPolicy:     defaultAckPolicy,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     defaultKeySource,
+				AckWait:       time.Minute,
+				MaxDeliver:    10,
+				MaxAckPending: -1,
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, backoff",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:      "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:   "foo",
+					ConfigKeyMaxDeliver: "4",
+					ConfigKeyBackOff:    "1s, 10s,1m",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:    defaultBufferSize,
+				DeliverPolicy: defaultDeliverPolicy,
+				AckPolicy:     defaultAckPolicy,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     defaultKeySource,
+				MaxDeliver:    4,
+				BackOff:       []time.Duration{time.Second, time.Second * 10, time.Minute},
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail, invalid ack wait",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:    "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject: "foo",
+					ConfigKeyAckWait:  "-1s",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, invalid max deliver",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:      "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:   "foo",
+					ConfigKeyMaxDeliver: "-2",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, invalid max ack pending",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:         "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:      "foo",
+					ConfigKeyMaxAckPending: "many",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, invalid backoff",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:      "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:   "foo",
+					ConfigKeyMaxDeliver: "4",
+					ConfigKeyBackOff:    "1s,0s",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, backoff with ack wait",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:      "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:   "foo",
+					ConfigKeyAckWait:    "1m",
+					ConfigKeyMaxDeliver: "4",
+					ConfigKeyBackOff:    "1s",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, backoff without enough max deliver",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:      "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:   "foo",
+					ConfigKeyMaxDeliver: "2",
+					ConfigKeyBackOff:    "1s,2s",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "success, delete consumer lifecycle",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:             "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:          "foo",
+					ConfigKeyConsumerLifecycle: "delete",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:        defaultBufferSize,
+				DeliverPolicy:     defaultDeliverPolicy,
+				AckPolicy:         defaultAckPolicy,
+				HeaderPrefix:      defaultHeaderPrefix,
+				KeySource:         defaultKeySource,
+				ConsumerLifecycle: defaultConsumerLifecycle,
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, retain consumer lifecycle",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:             "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:          "foo",
+					ConfigKeyDurable:           "conduit",
+					ConfigKeyConsumerLifecycle: "retain",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				Durable:           "conduit",
+				DeliverSubject:    "conduit.conduit",
+				BufferSize:        defaultBufferSize,
+				DeliverPolicy:     defaultDeliverPolicy,
+				AckPolicy:         defaultAckPolicy,
+				HeaderPrefix:      defaultHeaderPrefix,
+				KeySource:         defaultKeySource,
+				ConsumerLifecycle: jetstream.ConsumerLifecycleRetain,
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, drain consumer lifecycle",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:             "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:          "foo",
+					ConfigKeyDurable:           "conduit",
+					ConfigKeyConsumerLifecycle: "drain",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				Durable:           "conduit",
+				DeliverSubject:    "conduit.conduit",
+				BufferSize:        defaultBufferSize,
+				DeliverPolicy:     defaultDeliverPolicy,
+				AckPolicy:         defaultAckPolicy,
+				HeaderPrefix:      defaultHeaderPrefix,
+				KeySource:         defaultKeySource,
+				ConsumerLifecycle: jetstream.ConsumerLifecycleDrain,
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail, retain consumer lifecycle without durable",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:             "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:          "foo",
+					ConfigKeyConsumerLifecycle: "retain",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, invalid consumer lifecycle",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:             "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:          "foo",
+					ConfigKeyConsumerLifecycle: "forever",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "success, kv mode, all keys by default",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:     "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeyMode:     "kv",
+					config.KeyKVBucket: "settings",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       ">",
+					Mode:          config.ModeKV,
+					KVBucket:      "settings",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:    defaultBufferSize,
+				DeliverPolicy: defaultDeliverPolicy,
+				AckPolicy:     defaultAckPolicy,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     defaultKeySource,
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, kv mode, key pattern",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:     "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:  "services.*.config",
+					config.KeyMode:     "kv",
+					config.KeyKVBucket: "settings",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "services.*.config",
+					Mode:          config.ModeKV,
+					KVBucket:      "settings",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:    defaultBufferSize,
+				DeliverPolicy: defaultDeliverPolicy,
+				AckPolicy:     defaultAckPolicy,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     defaultKeySource,
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, core mode with a queue group",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:      "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:   "telemetry.>",
+					config.KeyMode:      "core",
+					ConfigKeyQueueGroup: "dashboards",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "telemetry.>",
+					Mode:          config.ModeCore,
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:    defaultBufferSize,
+				DeliverPolicy: defaultDeliverPolicy,
+				AckPolicy:     defaultAckPolicy,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     defaultKeySource,
+				QueueGroup:    "dashboards",
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail, subject template instead of subject",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:            "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubjectTemplate: "orders.{{.Operation}}",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "success, object mode, custom max content size",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:                "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeyMode:                "object",
+					config.KeyObjectBucket:        "files",
+					ConfigKeyObjectMaxContentSize: "4096",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Mode:          config.ModeObject,
+					ObjectBucket:  "files",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:           defaultBufferSize,
+				DeliverPolicy:        defaultDeliverPolicy,
+				AckPolicy:            defaultAckPolicy,
+				HeaderPrefix:         defaultHeaderPrefix,
+				KeySource:            defaultKeySource,
+				ObjectMaxContentSize: 4096,
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail, invalid object max content size",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:                "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeyMode:                "object",
+					config.KeyObjectBucket:        "files",
+					ConfigKeyObjectMaxContentSize: "-1",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, kv mode without bucket",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs: "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeyMode: "kv",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, jetstream mode without subject",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs: "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeyMode: "jetstream",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "success, stop at tail and idle",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:      "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:   "foo",
+					ConfigKeyStopAtTail: "true",
+					ConfigKeyTailAction: "idle",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:    defaultBufferSize,
+				DeliverPolicy: defaultDeliverPolicy,
+				AckPolicy:     defaultAckPolicy,
+				HeaderPrefix:  defaultHeaderPrefix,
+				KeySource:     defaultKeySource,
+				StopAtTail:    true,
+				TailAction:    jetstream.TailActionIdle,
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, position override",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:            "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:         "foo",
+					ConfigKeyPositionOverride: "true",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:       defaultBufferSize,
+				DeliverPolicy:    defaultDeliverPolicy,
+				AckPolicy:        defaultAckPolicy,
+				HeaderPrefix:     defaultHeaderPrefix,
+				KeySource:        defaultKeySource,
+				PositionOverride: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail, invalid position override",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:            "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:         "foo",
+					ConfigKeyPositionOverride: "yes",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "success, purged position policy",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:                "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:             "foo",
+					ConfigKeyPurgedPositionPolicy: "by_timestamp",
+				},
+			},
+			want: Config{
+				Config: config.Config{
+					URLs:          []string{"nats://127.0.0.1:1222", "nats://127.0.0.1:1223", "nats://127.0.0.1:1224"},
+					Subject:       "foo",
+					MaxReconnects: config.DefaultMaxReconnects,
+					ReconnectWait: config.DefaultReconnectWait,
+				},
+				BufferSize:           defaultBufferSize,
+				DeliverPolicy:        defaultDeliverPolicy,
+				AckPolicy:            defaultAckPolicy,
+				HeaderPrefix:         defaultHeaderPrefix,
+				KeySource:            defaultKeySource,
+				PurgedPositionPolicy: jetstream.PurgedPolicyByTimestamp,
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail, invalid purged position policy",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:                "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:             "foo",
+					ConfigKeyPurgedPositionPolicy: "skip",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, invalid tail action",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:      "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:   "foo",
+					ConfigKeyStopAtTail: "true",
+					ConfigKeyTailAction: "exit",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, stop at tail in core mode",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:      "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject:   "foo",
+					config.KeyMode:      "core",
+					ConfigKeyStopAtTail: "true",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
+		{
+			name: "fail, invalid mode",
+			args: args{
+				cfg: map[string]string{
+					config.KeyURLs:    "nats://127.0.0.1:1222,nats://127.0.0.1:1223,nats://127.0.0.1:1224",
+					config.KeySubject: "foo",
+					config.KeyMode:    "stream",
+				},
+			},
+			want:    Config{},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
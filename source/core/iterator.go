@@ -0,0 +1,162 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/source/message"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/nats-io/nats.go"
+)
+
+// Iterator is an iterator for the core NATS communication model.
+// It subscribes to a subject without JetStream, so messages are delivered at most once:
+// they cannot be acknowledged or replayed, and the ones published while the connector isn't subscribed,
+// dropped because of a slow consumer or left in the buffer on teardown are lost.
+type Iterator struct {
+	conn         *nats.Conn
+	messages     chan *nats.Msg
+	subscription *nats.Subscription
+	// sequence is a number of the last received message,
+	// it's used to build synthetic positions
+	sequence uint64
+	// headerPrefix is prepended to message header names
+	// when they're copied into a record's metadata.
+	headerPrefix string
+	keySource    message.KeySource
+	// errC receives async errors which interrupt waiting for messages
+	errC <-chan error
+}
+
+// IteratorParams contains incoming params for the NewIterator function.
+type IteratorParams struct {
+	Conn       *nats.Conn
+	BufferSize int
+	Subject    string
+	// QueueGroup is a name of a queue group to subscribe in, if empty the Iterator subscribes on its own.
+	QueueGroup   string
+	SDKPosition  sdk.Position
+	HeaderPrefix string
+	KeySource    message.KeySource
+	// ErrC is a channel of async errors, such as connection errors.
+	ErrC <-chan error
+}
+
+// NewIterator creates new instance of the Iterator.
+func NewIterator(params IteratorParams) (*Iterator, error) {
+	position, err := message.ParsePosition(params.SDKPosition, message.PositionModeCore)
+	if err != nil {
+		return nil, fmt.Errorf("parse position: %w", err)
+	}
+
+	messages := make(chan *nats.Msg, params.BufferSize)
+
+	var subscription *nats.Subscription
+	if params.QueueGroup != "" {
+		subscription, err = params.Conn.ChanQueueSubscribe(params.Subject, params.QueueGroup, messages)
+	} else {
+		subscription, err = params.Conn.ChanSubscribe(params.Subject, messages)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("chan subscribe: %w", err)
+	}
+
+	// make sure the server knows about the subscription before the Iterator is used,
+	// as messages published before that are not delivered
+	if err := params.Conn.Flush(); err != nil {
+		return nil, fmt.Errorf("flush subscription: %w", err)
+	}
+
+	return &Iterator{
+		conn:         params.Conn,
+		messages:     messages,
+		subscription: subscription,
+		sequence:     position.Sequence,
+		headerPrefix: params.HeaderPrefix,
+		keySource:    params.KeySource,
+		errC:         params.ErrC,
+	}, nil
+}
+
+// Next returns the next record converted from a message.
+// It blocks until there is a message, an async error occurs or the context is done.
+func (i *Iterator) Next(ctx context.Context) (sdk.Record, error) {
+	msg, _, err := message.Receive(ctx, i.messages, i.errC)
+	if err != nil {
+		return sdk.Record{}, err
+	}
+
+	sdkRecord, err := i.messageToRecord(ctx, msg)
+	if err != nil {
+		return sdk.Record{}, fmt.Errorf("convert message to record: %w", err)
+	}
+
+	return sdkRecord, nil
+}
+
+// Ack does nothing, as core NATS messages cannot be acknowledged.
+func (i *Iterator) Ack(sdk.Position) error {
+	return nil
+}
+
+// Stop unsubscribes from the subject and closes the connection.
+func (i *Iterator) Stop() error {
+	if i.subscription != nil {
+		if err := i.subscription.Unsubscribe(); err != nil {
+			return fmt.Errorf("unsubscribe: %w", err)
+		}
+	}
+
+	if i.conn != nil {
+		i.conn.Close()
+	}
+
+	return nil
+}
+
+// messageToRecord converts a *nats.Msg to a sdk.Record.
+// Core NATS messages have no timestamps, so the record is created at the moment the message is converted.
+func (i *Iterator) messageToRecord(ctx context.Context, msg *nats.Msg) (sdk.Record, error) {
+	i.sequence++
+
+	position, err := message.Position{Mode: message.PositionModeCore, Sequence: i.sequence}.MarshalSDKPosition()
+	if err != nil {
+		return sdk.Record{}, fmt.Errorf("marshal position: %w", err)
+	}
+
+	metadata := make(sdk.Metadata)
+	metadata.SetCreatedAt(time.Now())
+
+	message.SetHeaders(metadata, msg.Header, i.headerPrefix)
+
+	// the message info is set after the headers so they cannot override it
+	metadata[MetadataCollection] = msg.Subject
+	metadata[MetadataSubject] = msg.Subject
+
+	if msg.Reply != "" {
+		metadata[MetadataReply] = msg.Reply
+	}
+
+	key, err := i.keySource.ExtractKey(ctx, msg)
+	if err != nil {
+		return sdk.Record{}, fmt.Errorf("extract key: %w", err)
+	}
+
+	return sdk.Util.Source.NewRecordCreate(position, metadata, key, sdk.RawData(msg.Data)), nil
+}
@@ -0,0 +1,113 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/source/message"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/nats-io/nats.go"
+)
+
+func TestIterator_Next(t *testing.T) {
+	t.Parallel()
+
+	messages := make(chan *nats.Msg, 2)
+	messages <- &nats.Msg{
+		Subject: "telemetry.eu.cpu",
+		Reply:   "_INBOX.1",
+		Header:  nats.Header{"Host": []string{"node-1"}},
+		Data:    []byte(`{"value":0.7}`),
+	}
+	messages <- &nats.Msg{
+		Subject: "telemetry.us.cpu",
+		Data:    []byte(`{"value":0.2}`),
+	}
+
+	it := &Iterator{
+		messages: messages,
+		// the sequence continues from the last position
+		sequence:     41,
+		headerPrefix: "nats.header.",
+		keySource: message.KeySource{
+			Type:         message.KeySourceSubject,
+			SubjectToken: 1,
+		},
+	}
+
+	want := []struct {
+		key      string
+		position string
+		metadata sdk.Metadata
+	}{
+		{
+			key:      "eu",
+			position: `{"version":1,"mode":"core","sequence":42}`,
+			metadata: sdk.Metadata{
+				MetadataCollection: "telemetry.eu.cpu",
+				MetadataSubject:    "telemetry.eu.cpu",
+				MetadataReply:      "_INBOX.1",
+				"nats.header.Host": "node-1",
+			},
+		},
+		{
+			key:      "us",
+			position: `{"version":1,"mode":"core","sequence":43}`,
+			metadata: sdk.Metadata{
+				MetadataCollection: "telemetry.us.cpu",
+				MetadataSubject:    "telemetry.us.cpu",
+			},
+		},
+	}
+
+	for _, w := range want {
+		record, err := it.Next(context.Background())
+		if err != nil {
+			t.Fatalf("Iterator.Next() error = %v", err)
+		}
+
+		if record.Operation != sdk.OperationCreate {
+			t.Errorf("Iterator.Next() operation = %v, want %v", record.Operation, sdk.OperationCreate)
+		}
+
+		if string(record.Key.Bytes()) != w.key {
+			t.Errorf("Iterator.Next() key = %s, want %s", record.Key.Bytes(), w.key)
+		}
+
+		if string(record.Position) != w.position {
+			t.Errorf("Iterator.Next() position = %s, want %s", record.Position, w.position)
+		}
+
+		for key, value := range w.metadata {
+			if record.Metadata[key] != value {
+				t.Errorf("Iterator.Next() metadata[%q] = %q, want %q", key, record.Metadata[key], value)
+			}
+		}
+
+		if _, ok := record.Metadata[MetadataReply]; ok != (w.metadata[MetadataReply] != "") {
+			t.Errorf("Iterator.Next() metadata[%q] = %q, want it to be absent", MetadataReply, record.Metadata[MetadataReply])
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// there are no more messages, so the done context interrupts waiting
+	if _, err := it.Next(ctx); err == nil {
+		t.Errorf("Iterator.Next() error = nil, want %v", context.Canceled)
+	}
+}
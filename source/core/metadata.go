@@ -0,0 +1,25 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+const (
+	// MetadataCollection is a record metadata key for the name of a collection the record belongs to.
+	// The Iterator sets it to a subject of a message.
+	MetadataCollection = "opencdc.collection"
+	// MetadataSubject is a record metadata key for a subject of a message.
+	MetadataSubject = "nats.subject"
+	// MetadataReply is a record metadata key for a reply subject of a message.
+	MetadataReply = "nats.reply"
+)
@@ -0,0 +1,44 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"errors"
+
+	"github.com/nats-io/nats.go"
+)
+
+// recoverableAsyncErrors are async errors after which the subscription keeps receiving messages.
+var recoverableAsyncErrors = []error{
+	// messages were dropped, the server redelivers them once the ack wait expires
+	nats.ErrSlowConsumer,
+	// heartbeats were missed, the delivery resumes once the server is reachable again
+	nats.ErrConsumerNotActive,
+	// pending pull requests were dropped, the next fetch sends a new one
+	nats.ErrConsumerLeadershipChanged,
+}
+
+// isFatalAsyncError checks whether an async error stops the consumption.
+// Errors which are not known to be recoverable are considered fatal,
+// such as a deleted consumer, a permissions violation or an authorization error.
+func isFatalAsyncError(err error) bool {
+	for _, recoverable := range recoverableAsyncErrors {
+		if errors.Is(err, recoverable) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,90 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestIsFatalAsyncError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "recoverable, slow consumer",
+			err:  nats.ErrSlowConsumer,
+			want: false,
+		},
+		{
+			name: "recoverable, missed heartbeats",
+			err:  nats.ErrConsumerNotActive,
+			want: false,
+		},
+		{
+			name: "recoverable, wrapped leadership change",
+			err:  fmt.Errorf("fetch: %w", nats.ErrConsumerLeadershipChanged),
+			want: false,
+		},
+		{
+			name: "fatal, consumer deleted",
+			err:  nats.ErrConsumerDeleted,
+			want: true,
+		},
+		{
+			name: "fatal, permissions violation",
+			err:  errors.New(`nats: permissions violation for subscription to "foo"`),
+			want: true,
+		},
+		{
+			name: "fatal, authorization violation",
+			err:  nats.ErrAuthorization,
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := isFatalAsyncError(tt.err); got != tt.want {
+				t.Errorf("isFatalAsyncError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSource_reportError(t *testing.T) {
+	t.Parallel()
+
+	s := &Source{errC: make(chan error, 1)}
+
+	// the second error mustn't block
+	s.reportError(nats.ErrConsumerDeleted)
+	s.reportError(nats.ErrAuthorization)
+
+	if err := <-s.errC; !errors.Is(err, nats.ErrConsumerDeleted) {
+		t.Errorf("Source.reportError() error = %v, want %v", err, nats.ErrConsumerDeleted)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+var (
+	// ErrTailReached is returned by the Iterator which stops at the stream tail
+	// once all the messages up to the tail are acknowledged.
+	ErrTailReached = errors.New("reached the stream tail")
+	// ErrEndTimeReached is returned by the Iterator which stops at the end time
+	// once all the messages up to the end time are acknowledged.
+	ErrEndTimeReached = errors.New("reached the end time")
+)
+
+// TailAction defines what the Iterator does once it reaches the stream tail or the end time.
+type TailAction int
+
+const (
+	// TailActionStop makes the Iterator return the ErrTailReached or the ErrEndTimeReached.
+	TailActionStop TailAction = iota
+	// TailActionIdle makes the Iterator wait without returning records until it's stopped.
+	TailActionIdle
+)
+
+// getTailSequence returns a stream sequence of the last message of the subject,
+// or zero if the stream has no messages of the subject.
+func getTailSequence(jetstream nats.JetStreamContext, stream, subject string) (uint64, error) {
+	msg, err := jetstream.GetLastMsg(stream, subject)
+	if err != nil {
+		if errors.Is(err, nats.ErrMsgNotFound) {
+			return 0, nil
+		}
+
+		return 0, fmt.Errorf("get last message: %w", err)
+	}
+
+	return msg.Sequence, nil
+}
+
+// setBound makes the Iterator stop after the message with the given stream sequence,
+// an earlier bound is kept as the Iterator stops at whichever comes first.
+func (i *Iterator) setBound(sequence uint64, boundErr error) {
+	if i.bounded && i.boundSequence <= sequence {
+		return
+	}
+
+	i.bounded = true
+	i.boundSequence = sequence
+	i.boundErr = boundErr
+}
+
+// checkBound checks whether all the messages up to the bound sequence are read and acknowledged.
+// The server is asked only once all of them are acknowledged locally,
+// since messages delivered before the Iterator started can still wait for acknowledgements.
+func (i *Iterator) checkBound() (bool, error) {
+	if i.boundReached {
+		return true, nil
+	}
+
+	if i.lastSequence < i.boundSequence || i.hasUnackedUpTo(i.boundSequence) {
+		return false, nil
+	}
+
+	info, err := i.jetstream.ConsumerInfo(i.consumerInfo.Stream, i.consumerInfo.Name)
+	if err != nil {
+		return false, fmt.Errorf("get consumer info: %w", err)
+	}
+
+	// acknowledgements are sent asynchronously, so the server may not have received the last ones yet
+	i.boundReached = info.AckFloor.Stream >= i.boundSequence ||
+		(info.NumAckPending == 0 && info.Delivered.Stream >= i.boundSequence)
+
+	return i.boundReached, nil
+}
+
+// hasUnackedUpTo checks whether there are unacknowledged messages with stream sequences up to the given one.
+func (i *Iterator) hasUnackedUpTo(sequence uint64) bool {
+	i.Lock()
+	defer i.Unlock()
+
+	for unackSequence := range i.unackMessages {
+		if unackSequence <= sequence {
+			return true
+		}
+	}
+
+	return false
+}
+
+// atBound returns the error of the bound, or waits until an async error occurs or the context is done,
+// depending on the tail action.
+func (i *Iterator) atBound(ctx context.Context) error {
+	if i.tailAction == TailActionStop {
+		return i.boundErr
+	}
+
+	select {
+	case err := <-i.errC:
+		return fmt.Errorf("got an async error: %w", err)
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/nats-io/nats.go"
+)
+
+// updateConsumer applies the mutable settings of the given config to the existing consumer,
+// and fails if the settings which the server doesn't allow to change differ.
+func updateConsumer(
+	jetstream nats.JetStreamContext, consumerInfo *nats.ConsumerInfo, consumerConfig *nats.ConsumerConfig,
+) (*nats.ConsumerInfo, error) {
+	updatedConfig, changed, err := mergeConsumerConfig(consumerInfo.Config, *consumerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("consumer %q: %w, delete the consumer or use another durable name",
+			consumerInfo.Name, err)
+	}
+
+	if !changed {
+		return consumerInfo, nil
+	}
+
+	consumerInfo, err = jetstream.UpdateConsumer(consumerInfo.Stream, &updatedConfig)
+	if err != nil {
+		return nil, fmt.Errorf("update consumer: %w", err)
+	}
+
+	return consumerInfo, nil
+}
+
+// mergeConsumerConfig returns the actual config of a consumer with the mutable settings of the desired one,
+// and reports whether any of them changed.
+// The zero values of the desired settings keep the actual ones, as the server replaces them with its defaults.
+// The deliver policy and the start sequence or time are not compared,
+// as the existing consumer continues from its server-side position.
+func mergeConsumerConfig(actual, desired nats.ConsumerConfig) (nats.ConsumerConfig, bool, error) {
+	if err := checkImmutableConsumerConfig(actual, desired); err != nil {
+		return nats.ConsumerConfig{}, false, err
+	}
+
+	merged := actual
+	merged.DeliverSubject = desired.DeliverSubject
+
+	// the server uses the first backoff duration as the ack wait, so they're set together
+	if len(desired.BackOff) > 0 {
+		merged.BackOff = desired.BackOff
+		merged.AckWait = desired.BackOff[0]
+	} else if desired.AckWait != 0 {
+		merged.BackOff = nil
+		merged.AckWait = desired.AckWait
+	}
+
+	if desired.MaxDeliver != 0 {
+		merged.MaxDeliver = desired.MaxDeliver
+	}
+
+	if desired.MaxAckPending != 0 {
+		merged.MaxAckPending = desired.MaxAckPending
+	}
+
+	return merged, !reflect.DeepEqual(merged, actual), nil
+}
+
+// checkImmutableConsumerConfig checks that the settings which the server doesn't allow to change are the same.
+func checkImmutableConsumerConfig(actual, desired nats.ConsumerConfig) error {
+	// pull consumers have no deliver subject
+	switch isPull := actual.DeliverSubject == ""; {
+	case isPull && desired.DeliverSubject != "":
+		return fmt.Errorf("a pull consumer cannot be changed to a push one")
+	case !isPull && desired.DeliverSubject == "":
+		return fmt.Errorf("a push consumer cannot be changed to a pull one")
+	}
+
+	if actual.FilterSubject != desired.FilterSubject {
+		return fmt.Errorf("the filter subject %q cannot be changed to %q", actual.FilterSubject, desired.FilterSubject)
+	}
+
+	if actual.AckPolicy != desired.AckPolicy {
+		return fmt.Errorf("the ack policy %s cannot be changed to %s", actual.AckPolicy, desired.AckPolicy)
+	}
+
+	return nil
+}
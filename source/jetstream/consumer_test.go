@@ -0,0 +1,150 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func Test_mergeConsumerConfig(t *testing.T) {
+	t.Parallel()
+
+	actual := nats.ConsumerConfig{
+		Durable:       "conduit",
+		FilterSubject: "orders",
+		AckPolicy:     nats.AckExplicitPolicy,
+		AckWait:       5 * time.Second,
+		MaxDeliver:    -1,
+		MaxAckPending: 1000,
+	}
+
+	tests := []struct {
+		name        string
+		desired     nats.ConsumerConfig
+		want        nats.ConsumerConfig
+		wantChanged bool
+		wantErr     bool
+	}{
+		{
+			name: "success, unchanged",
+			desired: nats.ConsumerConfig{
+				Durable:       "conduit",
+				FilterSubject: "orders",
+				AckPolicy:     nats.AckExplicitPolicy,
+				AckWait:       5 * time.Second,
+			},
+			want:        actual,
+			wantChanged: false,
+		},
+		{
+			name: "success, mutable settings changed",
+			desired: nats.ConsumerConfig{
+				Durable:       "conduit",
+				FilterSubject: "orders",
+				AckPolicy:     nats.AckExplicitPolicy,
+				AckWait:       10 * time.Second,
+				MaxDeliver:    5,
+				MaxAckPending: 100,
+			},
+			want: nats.ConsumerConfig{
+				Durable:       "conduit",
+				FilterSubject: "orders",
+				AckPolicy:     nats.AckExplicitPolicy,
+				AckWait:       10 * time.Second,
+				MaxDeliver:    5,
+				MaxAckPending: 100,
+			},
+			wantChanged: true,
+		},
+		{
+			name: "success, backoff sets the ack wait",
+			desired: nats.ConsumerConfig{
+				Durable:       "conduit",
+				FilterSubject: "orders",
+				AckPolicy:     nats.AckExplicitPolicy,
+				BackOff:       []time.Duration{time.Second, time.Minute},
+			},
+			want: nats.ConsumerConfig{
+				Durable:       "conduit",
+				FilterSubject: "orders",
+				AckPolicy:     nats.AckExplicitPolicy,
+				AckWait:       time.Second,
+				BackOff:       []time.Duration{time.Second, time.Minute},
+				MaxDeliver:    -1,
+				MaxAckPending: 1000,
+			},
+			wantChanged: true,
+		},
+		{
+			name: "fail, filter subject changed",
+			desired: nats.ConsumerConfig{
+				Durable:       "conduit",
+				FilterSubject: "payments",
+				AckPolicy:     nats.AckExplicitPolicy,
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail, ack policy changed",
+			desired: nats.ConsumerConfig{
+				Durable:       "conduit",
+				FilterSubject: "orders",
+				AckPolicy:     nats.AckAllPolicy,
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail, pull changed to push",
+			desired: nats.ConsumerConfig{
+				Durable:        "conduit",
+				DeliverSubject: "orders.conduit",
+				FilterSubject:  "orders",
+				AckPolicy:      nats.AckExplicitPolicy,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, changed, err := mergeConsumerConfig(actual, tt.desired)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("mergeConsumerConfig() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if changed != tt.wantChanged {
+				t.Errorf("mergeConsumerConfig() changed = %v, want %v", changed, tt.wantChanged)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeConsumerConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -15,12 +15,14 @@
 package jetstream
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/source/message"
 	sdk "github.com/conduitio/conduit-connector-sdk"
 	"github.com/nats-io/nats.go"
 )
@@ -28,6 +30,40 @@ import (
 // heartbeatTimeout is a default heartbeat timeout for push consumers.
 const heartbeatTimeout = 2 * time.Second
 
+// boundCheckInterval is how often a bounded Iterator checks the bound while it waits for messages.
+const boundCheckInterval = time.Second
+
+const (
+	// drainTimeout is the maximum time to wait for a subscription to be drained.
+	drainTimeout = 5 * time.Second
+	// drainCheckInterval is how often the drained subscription state is checked.
+	drainCheckInterval = 50 * time.Millisecond
+)
+
+// ConsumerType defines a type of JetStream consumer the Iterator uses.
+type ConsumerType int
+
+const (
+	// ConsumerTypePush makes the server push messages to a deliver subject.
+	ConsumerTypePush ConsumerType = iota
+	// ConsumerTypePull makes the Iterator fetch batches of messages on demand.
+	ConsumerTypePull
+)
+
+// ConsumerLifecycle defines what the Iterator does with its durable consumer when it stops.
+type ConsumerLifecycle int
+
+const (
+	// ConsumerLifecycleDelete deletes the consumer.
+	ConsumerLifecycleDelete ConsumerLifecycle = iota
+	// ConsumerLifecycleRetain keeps the consumer along with its state,
+	// so the next Iterator with the same durable name continues from where this one left off.
+	ConsumerLifecycleRetain
+	// ConsumerLifecycleDrain keeps the consumer, and negatively acknowledges all unacknowledged messages,
+	// so the server redelivers them right away instead of waiting for the ack wait to expire.
+	ConsumerLifecycleDrain
+)
+
 // Iterator is a iterator for JetStream communication model.
 // It receives message from NATS JetStream.
 type Iterator struct {
@@ -35,100 +71,277 @@ type Iterator struct {
 
 	conn          *nats.Conn
 	messages      chan *nats.Msg
-	unackMessages []*nats.Msg
+	unackMessages map[uint64]*nats.Msg
 	jetstream     nats.JetStreamContext
 	consumerInfo  *nats.ConsumerInfo
 	subscription  *nats.Subscription
+	consumerType  ConsumerType
+	// subject is the filter subject of the consumer, it's stored within positions
+	subject string
+	// consumerLifecycle defines what happens with the consumer when the Iterator stops
+	consumerLifecycle ConsumerLifecycle
+	// resumeSequence is a stream sequence of the last message processed before the Iterator started,
+	// messages up to this sequence are acknowledged and skipped
+	resumeSequence uint64
+	batchSize      int
+	maxWait        time.Duration
+	// headerPrefix is prepended to message header names
+	// when they're copied into a record's metadata.
+	headerPrefix string
+	keySource    message.KeySource
+	// errC receives async errors which interrupt waiting for messages
+	errC <-chan error
+	// endTime makes the Iterator stop before the first message with a later timestamp, if it's not zero
+	endTime time.Time
+	// bounded makes the Iterator stop once all the messages up to the boundSequence are acknowledged,
+	// the boundErr is returned then if the tailAction is TailActionStop
+	bounded       bool
+	boundSequence uint64
+	boundErr      error
+	boundReached  bool
+	tailAction    TailAction
+	// lastSequence is the highest stream sequence of received messages
+	lastSequence uint64
 }
 
 // IteratorParams contains incoming params for the NewIterator function.
 type IteratorParams struct {
-	Conn           *nats.Conn
-	BufferSize     int
-	Durable        string
-	DeliverSubject string
-	Subject        string
-	SDKPosition    sdk.Position
-	DeliverPolicy  nats.DeliverPolicy
-	AckPolicy      nats.AckPolicy
+	Conn              *nats.Conn
+	BufferSize        int
+	Durable           string
+	DeliverSubject    string
+	Subject           string
+	SDKPosition       sdk.Position
+	DeliverPolicy     nats.DeliverPolicy
+	StartSequence     uint64
+	StartTime         time.Time
+	AckPolicy         nats.AckPolicy
+	AckWait           time.Duration
+	MaxDeliver        int
+	BackOff           []time.Duration
+	MaxAckPending     int
+	ConsumerType      ConsumerType
+	ConsumerLifecycle ConsumerLifecycle
+	BatchSize         int
+	MaxWait           time.Duration
+	HeaderPrefix      string
+	KeySource         message.KeySource
+	// ErrC is a channel of async errors, such as connection errors.
+	ErrC <-chan error
+	// StopAtTail makes the Iterator stop at the last message of the subject
+	// which was in the stream when the Iterator started.
+	StopAtTail bool
+	// EndTime makes the Iterator stop before the first message with a later timestamp.
+	// The end time stored in the position is used if it's zero.
+	EndTime time.Time
+	// TailAction defines what the Iterator does once it reaches the tail or the end time.
+	TailAction TailAction
+	// PositionOverride makes the Iterator discard a position which belongs to a different stream or subject,
+	// instead of failing.
+	PositionOverride bool
+	// PurgedPolicy defines what the Iterator does if messages following the position were removed from the stream.
+	PurgedPolicy PurgedPolicy
 }
 
-// getSubscribeOptions returns a NATS subscribe options based on the IteratorParams's fields.
-func (p IteratorParams) getSubscribeOptions() ([]nats.SubOpt, error) {
-	var opts []nats.SubOpt
-
-	position, err := parsePosition(p.SDKPosition)
-	if err != nil {
-		return nil, fmt.Errorf("parse position: %w", err)
+// getConsumerConfig returns a JetStream consumer config based on the IteratorParams's fields.
+func (p IteratorParams) getConsumerConfig(position position) *nats.ConsumerConfig {
+	consumerConfig := &nats.ConsumerConfig{
+		Durable:       p.Durable,
+		FilterSubject: p.Subject,
+		DeliverPolicy: p.DeliverPolicy,
+		AckPolicy:     p.AckPolicy,
+		// zero values make the server use its defaults
+		AckWait:       p.AckWait,
+		MaxDeliver:    p.MaxDeliver,
+		BackOff:       p.BackOff,
+		MaxAckPending: p.MaxAckPending,
+		ReplayPolicy:  nats.ReplayInstantPolicy,
 	}
 
-	// if the position has a non-zero OptSeq
+	// if the position has a non-zero Sequence
 	// the connector will start consuming from that position
-	if position.OptSeq != 0 {
+	if position.Sequence != 0 {
 		// add 1 to the sequence in order to skip the consumed message at this position
 		// and start consuming new messages
 		// deliverPolicy in this case will become a DeliverByStartSequencePolicy.
-		opts = append(opts, nats.StartSequence(position.OptSeq+1))
+		consumerConfig.DeliverPolicy = nats.DeliverByStartSequencePolicy
+		consumerConfig.OptStartSeq = position.Sequence + 1
+	} else if position.Timestamp != nil {
+		// every position stores the timestamp of its message, but the Sequence is zero here only if
+		// the by_timestamp purged policy reset it, so the connector resumes from the first message not older than it
+		consumerConfig.DeliverPolicy = nats.DeliverByStartTimePolicy
+		consumerConfig.OptStartTime = position.Timestamp
 	} else {
 		switch p.DeliverPolicy {
-		case nats.DeliverAllPolicy:
-			opts = append(opts, nats.DeliverAll())
-		case nats.DeliverNewPolicy:
-			opts = append(opts, nats.DeliverNew())
+		case nats.DeliverByStartSequencePolicy:
+			consumerConfig.OptStartSeq = p.StartSequence
+		case nats.DeliverByStartTimePolicy:
+			consumerConfig.OptStartTime = &p.StartTime
 		}
 	}
 
-	switch p.AckPolicy {
-	case nats.AckAllPolicy:
-		opts = append(opts, nats.AckAll())
-	case nats.AckExplicitPolicy:
-		opts = append(opts, nats.AckExplicit())
-	case nats.AckNonePolicy:
-		opts = append(opts, nats.AckNone())
+	// pull consumers have neither a deliver subject nor flow control,
+	// messages are requested explicitly by the Iterator
+	if p.ConsumerType == ConsumerTypePush {
+		consumerConfig.DeliverSubject = p.DeliverSubject
+		consumerConfig.FlowControl = true
+		consumerConfig.Heartbeat = heartbeatTimeout
 	}
 
-	opts = append(opts,
-		nats.Durable(p.Durable),
-		nats.ReplayInstant(),
-		nats.DeliverSubject(p.DeliverSubject),
-		nats.EnableFlowControl(),
-		nats.IdleHeartbeat(heartbeatTimeout),
-	)
-
-	return opts, nil
+	return consumerConfig
 }
 
 // NewIterator creates new instance of the Iterator.
-func NewIterator(params IteratorParams) (*Iterator, error) {
+func NewIterator(ctx context.Context, params IteratorParams) (*Iterator, error) {
 	jetstream, err := params.Conn.JetStream()
 	if err != nil {
 		return nil, fmt.Errorf("get jetstream context: %w", err)
 	}
 
-	subscribeOpts, err := params.getSubscribeOptions()
+	stream, err := jetstream.StreamNameBySubject(params.Subject)
 	if err != nil {
-		return nil, fmt.Errorf("get consumer options: %w", err)
+		return nil, fmt.Errorf("get stream name by subject: %w", err)
 	}
 
-	messages := make(chan *nats.Msg, params.BufferSize)
-	subscription, err := jetstream.ChanSubscribe(params.Subject, messages, subscribeOpts...)
+	position, err := getResumePosition(ctx, params, stream)
 	if err != nil {
-		return nil, fmt.Errorf("chan subscribe: %w", err)
+		return nil, err
 	}
 
-	consumerInfo, err := subscription.ConsumerInfo()
+	position, err = resolvePurgedSequence(ctx, jetstream, stream, position, params.PurgedPolicy)
 	if err != nil {
-		return nil, fmt.Errorf("get consumer info: %w", err)
+		return nil, err
+	}
+
+	consumerInfo, err := getOrAddConsumer(jetstream, stream, params.getConsumerConfig(position))
+	if err != nil {
+		return nil, fmt.Errorf("get or add consumer: %w", err)
+	}
+
+	// the Iterator manages the consumer on its own,
+	// so the subscription only binds to it and never deletes it
+	bindOpt := nats.Bind(consumerInfo.Stream, consumerInfo.Name)
+
+	var (
+		messages     chan *nats.Msg
+		subscription *nats.Subscription
+	)
+
+	switch params.ConsumerType {
+	case ConsumerTypePush:
+		messages = make(chan *nats.Msg, params.BufferSize)
+
+		subscription, err = jetstream.ChanSubscribe(params.Subject, messages, bindOpt)
+		if err != nil {
+			return nil, fmt.Errorf("chan subscribe: %w", err)
+		}
+
+	case ConsumerTypePull:
+		// the buffer holds no more than one fetched batch at a time
+		messages = make(chan *nats.Msg, params.BatchSize)
+
+		subscription, err = jetstream.PullSubscribe(params.Subject, "", bindOpt)
+		if err != nil {
+			return nil, fmt.Errorf("pull subscribe: %w", err)
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown consumer type %d", params.ConsumerType)
+	}
+
+	iterator := &Iterator{
+		conn:              params.Conn,
+		messages:          messages,
+		unackMessages:     make(map[uint64]*nats.Msg),
+		jetstream:         jetstream,
+		consumerInfo:      consumerInfo,
+		subscription:      subscription,
+		consumerType:      params.ConsumerType,
+		subject:           params.Subject,
+		consumerLifecycle: params.ConsumerLifecycle,
+		resumeSequence:    position.Sequence,
+		batchSize:         params.BatchSize,
+		maxWait:           params.MaxWait,
+		headerPrefix:      params.HeaderPrefix,
+		keySource:         params.KeySource,
+		errC:              params.ErrC,
+		endTime:           params.EndTime,
+		tailAction:        params.TailAction,
+		// messages delivered before the Iterator started are not redelivered once they're acknowledged
+		lastSequence: consumerInfo.Delivered.Stream,
+	}
+
+	// the end time is kept in the position, so a resumed replay stops at the same time
+	if iterator.endTime.IsZero() && position.EndTime != nil {
+		iterator.endTime = *position.EndTime
+	}
+
+	if params.StopAtTail {
+		tailSequence, err := getTailSequence(jetstream, consumerInfo.Stream, params.Subject)
+		if err != nil {
+			return nil, fmt.Errorf("get tail sequence: %w", err)
+		}
+
+		iterator.setBound(tailSequence, ErrTailReached)
+	}
+
+	return iterator, nil
+}
+
+// getResumePosition parses the position the Iterator resumes from and checks that it belongs to the stream
+// and the subject. A mismatched position is discarded if the PositionOverride is set,
+// so the Iterator starts according to the deliver policy.
+func getResumePosition(ctx context.Context, params IteratorParams, stream string) (position, error) {
+	resumePosition, err := parsePosition(params.SDKPosition)
+	if err != nil {
+		return position{}, fmt.Errorf("parse position: %w", err)
+	}
+
+	if err := resumePosition.verify(stream, params.Subject); err != nil {
+		if !params.PositionOverride {
+			return position{}, fmt.Errorf("verify position: %w", err)
+		}
+
+		sdk.Logger(ctx).Warn().Err(err).Msg("discarding the position, the deliver policy is used instead")
+
+		return position{}, nil
+	}
+
+	// the stream sequence is valid for any consumer, and generated durable names change on each start,
+	// so a different consumer is only reported
+	if resumePosition.Consumer != "" && resumePosition.Consumer != params.Durable {
+		sdk.Logger(ctx).Warn().
+			Str("position_consumer", resumePosition.Consumer).
+			Str("consumer", params.Durable).
+			Msg("the position was stored by a different consumer")
 	}
 
-	return &Iterator{
-		conn:          params.Conn,
-		messages:      messages,
-		unackMessages: make([]*nats.Msg, 0),
-		jetstream:     jetstream,
-		consumerInfo:  consumerInfo,
-		subscription:  subscription,
-	}, nil
+	return resumePosition, nil
+}
+
+// getOrAddConsumer returns info of the existing durable consumer or creates a new one
+// within the stream.
+// An existing consumer keeps its state, so the consumption continues from the server-side position,
+// and its mutable settings, such as the ack wait, are updated to the given config.
+func getOrAddConsumer(
+	jetstream nats.JetStreamContext, stream string, consumerConfig *nats.ConsumerConfig,
+) (*nats.ConsumerInfo, error) {
+	consumerInfo, err := jetstream.ConsumerInfo(stream, consumerConfig.Durable)
+	switch {
+	case err == nil:
+		return updateConsumer(jetstream, consumerInfo, consumerConfig)
+
+	case errors.Is(err, nats.ErrConsumerNotFound):
+		consumerInfo, err = jetstream.AddConsumer(stream, consumerConfig)
+		if err != nil {
+			return nil, fmt.Errorf("add consumer: %w", err)
+		}
+
+		return consumerInfo, nil
+
+	default:
+		return nil, fmt.Errorf("get consumer info: %w", err)
+	}
 }
 
 // HasNext checks is the iterator has messages.
@@ -136,27 +349,175 @@ func (i *Iterator) HasNext() bool {
 	return len(i.messages) > 0
 }
 
+// fetch requests the next batch of messages from a pull consumer
+// and puts them into the underlying messages channel.
+// It waits for messages no longer than the configured max wait duration,
+// and returns right away if an async error occurs in the meantime.
+func (i *Iterator) fetch(ctx context.Context) error {
+	fetchCtx, cancel := context.WithTimeout(ctx, i.maxWait)
+	defer cancel()
+
+	type fetchResult struct {
+		messages []*nats.Msg
+		err      error
+	}
+
+	resultC := make(chan fetchResult, 1)
+	go func() {
+		messages, err := i.subscription.Fetch(i.batchSize, nats.Context(fetchCtx))
+		resultC <- fetchResult{messages: messages, err: err}
+	}()
+
+	var result fetchResult
+	select {
+	case err := <-i.errC:
+		// the fetch request is cancelled, and the messages it has already received are kept,
+		// so they're handled according to the consumer lifecycle on stop
+		cancel()
+		i.buffer((<-resultC).messages)
+
+		return fmt.Errorf("got an async error: %w", err)
+
+	case result = <-resultC:
+	}
+
+	if result.err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// there were no messages within the max wait duration
+		if errors.Is(result.err, nats.ErrTimeout) || errors.Is(result.err, context.DeadlineExceeded) {
+			return nil
+		}
+
+		return fmt.Errorf("fetch messages: %w", result.err)
+	}
+
+	i.buffer(result.messages)
+
+	return nil
+}
+
+// buffer puts fetched messages into the underlying messages channel.
+func (i *Iterator) buffer(messages []*nats.Msg) {
+	for _, msg := range messages {
+		i.messages <- msg
+	}
+}
+
 // Next returns the next record from the underlying messages channel.
-// It also appends messages to a unackMessages slice if the AckPolicy is not equal to AckNonePolicy.
+// It blocks until there is a message, an async error occurs or the context is done.
+// If the Iterator is bounded by the stream tail or the end time, it returns no records after the bound.
+// It also puts messages to the unackMessages map if the AckPolicy is not equal to AckNonePolicy.
 func (i *Iterator) Next(ctx context.Context) (sdk.Record, error) {
-	select {
-	case msg := <-i.messages:
-		sdkRecord, err := i.messageToRecord(msg)
+	for {
+		msg, err := i.receive(ctx)
+		if err != nil {
+			return sdk.Record{}, err
+		}
+
+		// there was no message yet, a fetch or a bound check happened instead
+		if msg == nil {
+			continue
+		}
+
+		metadata, err := msg.Metadata()
+		if err != nil {
+			return sdk.Record{}, fmt.Errorf("get message metadata: %w", err)
+		}
+
+		if metadata.Sequence.Stream > i.lastSequence {
+			i.lastSequence = metadata.Sequence.Stream
+		}
+
+		// messages are stored in the order of their timestamps,
+		// so the first message after the end time bounds the Iterator
+		if !i.endTime.IsZero() && metadata.Timestamp.After(i.endTime) {
+			i.setBound(metadata.Sequence.Stream-1, ErrEndTimeReached)
+		}
+
+		// messages after the bound are left unacknowledged, so they're redelivered to the next consumer
+		if i.bounded && metadata.Sequence.Stream > i.boundSequence {
+			continue
+		}
+
+		// a retained consumer redelivers messages which were processed
+		// but whose acknowledgements didn't reach the server
+		if metadata.Sequence.Stream <= i.resumeSequence {
+			if err := i.ackProcessed(msg); err != nil {
+				return sdk.Record{}, fmt.Errorf("ack processed message: %w", err)
+			}
+
+			continue
+		}
+
+		sdkRecord, err := i.messageToRecord(ctx, msg)
 		if err != nil {
 			return sdk.Record{}, fmt.Errorf("convert message to record: %w", err)
 		}
 
 		if i.consumerInfo.Config.AckPolicy != nats.AckNonePolicy {
+			// a redelivered message replaces the previous delivery,
+			// so the acknowledgement goes to the latest one
 			i.Lock()
-			i.unackMessages = append(i.unackMessages, msg)
+			i.unackMessages[metadata.Sequence.Stream] = msg
 			i.Unlock()
 		}
 
 		return sdkRecord, nil
+	}
+}
+
+// receive returns the next message, or a nil message if it fetched messages or waited for the bound check interval.
+// A bounded Iterator checks the bound while waiting,
+// since the bound is reached once acknowledgements arrive rather than messages.
+func (i *Iterator) receive(ctx context.Context) (*nats.Msg, error) {
+	if i.bounded {
+		reached, err := i.checkBound()
+		if err != nil {
+			return nil, fmt.Errorf("check bound: %w", err)
+		}
+
+		if reached {
+			return nil, i.atBound(ctx)
+		}
+	}
+
+	// pull consumers request messages only when the previous batch is drained
+	if i.consumerType == ConsumerTypePull && !i.HasNext() {
+		return nil, i.fetch(ctx)
+	}
+
+	receiveCtx := ctx
+	if i.bounded {
+		var cancel context.CancelFunc
+		receiveCtx, cancel = context.WithTimeout(ctx, boundCheckInterval)
+		defer cancel()
+	}
+
+	msg, ok, err := message.Receive(receiveCtx, i.messages, i.errC)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil:
+		return nil, nil
 
-	case <-ctx.Done():
-		return sdk.Record{}, ctx.Err()
+	case err != nil:
+		return nil, err
+
+	case !ok:
+		return nil, errors.New("messages channel is closed")
+	}
+
+	return msg, nil
+}
+
+// ackProcessed acknowledges a message which was processed before the Iterator started.
+func (i *Iterator) ackProcessed(msg *nats.Msg) error {
+	if i.consumerInfo.Config.AckPolicy == nats.AckNonePolicy {
+		return nil
 	}
+
+	return msg.Ack()
 }
 
 // Ack acknowledges a message at the given position.
@@ -166,32 +527,71 @@ func (i *Iterator) Ack(sdkPosition sdk.Position) error {
 		return nil
 	}
 
+	position, err := parsePosition(sdkPosition)
+	if err != nil {
+		return fmt.Errorf("parse position: %w", err)
+	}
+
 	i.Lock()
 	defer i.Unlock()
 
-	if err := i.canAck(sdkPosition); err != nil {
-		return fmt.Errorf("message cannot be acknowledged: %w", err)
+	msg, ok := i.unackMessages[position.Sequence]
+	if !ok {
+		// the message has already been acknowledged,
+		// either directly or by a later message if the AckPolicy is AckAllPolicy
+		return nil
 	}
 
-	if err := i.unackMessages[0].Ack(); err != nil {
+	if err := msg.Ack(); err != nil {
 		return fmt.Errorf("ack message: %w", err)
 	}
 
-	// remove acknowledged message from the slice
-	i.unackMessages = i.unackMessages[1:]
+	delete(i.unackMessages, position.Sequence)
+
+	// acknowledging a message with AckAllPolicy acknowledges all the previous messages as well
+	if i.consumerInfo.Config.AckPolicy == nats.AckAllPolicy {
+		for sequence := range i.unackMessages {
+			if sequence < position.Sequence {
+				delete(i.unackMessages, sequence)
+			}
+		}
+	}
 
 	return nil
 }
 
-// Stop stops the Iterator, unsubscribes from a subject.
+// Stop stops the Iterator, unsubscribes from a subject
+// and deletes or keeps the consumer depending on the consumer lifecycle.
 func (i *Iterator) Stop() (err error) {
 	if i.subscription != nil {
-		// it will delete a consumer belonged to the subscription as well
-		if err = i.subscription.Unsubscribe(); err != nil {
+		// the subscription is bound to the consumer, so neither of these deletes it
+		if i.consumerLifecycle == ConsumerLifecycleDrain {
+			err = i.drainSubscription()
+		} else {
+			err = i.subscription.Unsubscribe()
+		}
+
+		if err != nil {
 			return fmt.Errorf("unsubscribe: %w", err)
 		}
 	}
 
+	switch i.consumerLifecycle {
+	case ConsumerLifecycleDelete:
+		err = i.jetstream.DeleteConsumer(i.consumerInfo.Stream, i.consumerInfo.Name)
+		if err != nil && !errors.Is(err, nats.ErrConsumerNotFound) {
+			return fmt.Errorf("delete consumer: %w", err)
+		}
+
+	case ConsumerLifecycleDrain:
+		if err = i.nakPending(); err != nil {
+			return fmt.Errorf("nak pending messages: %w", err)
+		}
+
+	case ConsumerLifecycleRetain:
+		// nothing to do, the consumer keeps its state on the server
+	}
+
 	close(i.messages)
 
 	if i.conn != nil {
@@ -201,28 +601,54 @@ func (i *Iterator) Stop() (err error) {
 	return nil
 }
 
-// canAck checks if a message at the given position can be acknowledged.
-func (i *Iterator) canAck(sdkPosition sdk.Position) error {
-	if len(i.unackMessages) == 0 {
-		return fmt.Errorf("requested ack for %q but no unacknowledged messages found", sdkPosition)
+// drainSubscription unsubscribes and waits until the messages
+// which were already sent by the server are put into the messages channel.
+func (i *Iterator) drainSubscription() error {
+	if err := i.subscription.Drain(); err != nil {
+		return fmt.Errorf("drain subscription: %w", err)
 	}
 
-	position, err := i.getMessagePosition(i.unackMessages[0])
-	if err != nil {
-		return fmt.Errorf("get position: %w", err)
+	timeout := time.After(drainTimeout)
+	for i.subscription.IsValid() {
+		select {
+		case <-time.After(drainCheckInterval):
+		case <-timeout:
+			return errors.New("drain subscription: timed out")
+		}
 	}
 
-	if !bytes.Equal(position, sdkPosition) {
-		return fmt.Errorf(
-			"ack is out-of-order, requested ack for %q, but first unack. Message is %q", sdkPosition, position,
-		)
+	return nil
+}
+
+// nakPending negatively acknowledges both unread and unacknowledged messages,
+// so the server redelivers them without waiting for the ack wait to expire.
+func (i *Iterator) nakPending() error {
+	if i.consumerInfo.Config.AckPolicy == nats.AckNonePolicy {
+		return nil
+	}
+
+	for len(i.messages) > 0 {
+		if err := (<-i.messages).Nak(); err != nil {
+			return fmt.Errorf("nak unread message: %w", err)
+		}
+	}
+
+	i.Lock()
+	defer i.Unlock()
+
+	for sequence, msg := range i.unackMessages {
+		if err := msg.Nak(); err != nil {
+			return fmt.Errorf("nak unacknowledged message: %w", err)
+		}
+
+		delete(i.unackMessages, sequence)
 	}
 
 	return nil
 }
 
 // messageToRecord converts a *nats.Msg to a sdk.Record.
-func (i *Iterator) messageToRecord(msg *nats.Msg) (sdk.Record, error) {
+func (i *Iterator) messageToRecord(ctx context.Context, msg *nats.Msg) (sdk.Record, error) {
 	position, err := i.getMessagePosition(msg)
 	if err != nil {
 		return sdk.Record{}, fmt.Errorf("get position: %w", err)
@@ -242,7 +668,28 @@ func (i *Iterator) messageToRecord(msg *nats.Msg) (sdk.Record, error) {
 	sdkMetadata := make(sdk.Metadata)
 	sdkMetadata.SetCreatedAt(metadata.Timestamp)
 
-	return sdk.Util.Source.NewRecordCreate(position, sdkMetadata, nil, sdk.RawData(msg.Data)), nil
+	message.SetHeaders(sdkMetadata, msg.Header, i.headerPrefix)
+	// the message info is set after the headers so they cannot override it
+	setMessageInfo(sdkMetadata, msg.Subject, metadata)
+
+	key, err := i.keySource.ExtractKey(ctx, msg)
+	if err != nil {
+		return sdk.Record{}, fmt.Errorf("extract key: %w", err)
+	}
+
+	return sdk.Util.Source.NewRecordCreate(position, sdkMetadata, key, sdk.RawData(msg.Data)), nil
+}
+
+// setMessageInfo sets the message subject, stream and consumer info to the sdk.Metadata.
+func setMessageInfo(sdkMetadata sdk.Metadata, subject string, metadata *nats.MsgMetadata) {
+	sdkMetadata[MetadataCollection] = subject
+	sdkMetadata[MetadataSubject] = subject
+	sdkMetadata[MetadataStream] = metadata.Stream
+	sdkMetadata[MetadataConsumer] = metadata.Consumer
+	sdkMetadata[MetadataStreamSequence] = strconv.FormatUint(metadata.Sequence.Stream, 10)
+	sdkMetadata[MetadataConsumerSequence] = strconv.FormatUint(metadata.Sequence.Consumer, 10)
+	sdkMetadata[MetadataNumDelivered] = strconv.FormatUint(metadata.NumDelivered, 10)
+	sdkMetadata[MetadataNumPending] = strconv.FormatUint(metadata.NumPending, 10)
 }
 
 // getMessagePosition returns a position of a message in the form of sdk.Position.
@@ -253,7 +700,15 @@ func (i *Iterator) getMessagePosition(msg *nats.Msg) (sdk.Position, error) {
 	}
 
 	position := position{
-		OptSeq: metadata.Sequence.Stream,
+		Stream:    metadata.Stream,
+		Subject:   i.subject,
+		Consumer:  metadata.Consumer,
+		Sequence:  metadata.Sequence.Stream,
+		Timestamp: &metadata.Timestamp,
+	}
+
+	if !i.endTime.IsZero() {
+		position.EndTime = &i.endTime
 	}
 
 	sdkPosition, err := position.marshalSDKPosition()
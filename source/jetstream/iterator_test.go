@@ -15,8 +15,13 @@
 package jetstream
 
 import (
+	"context"
+	"reflect"
+	"strconv"
 	"testing"
+	"time"
 
+	sdk "github.com/conduitio/conduit-connector-sdk"
 	"github.com/nats-io/nats.go"
 )
 
@@ -90,3 +95,141 @@ func TestIterator_HasNext(t *testing.T) {
 		})
 	}
 }
+
+func TestIterator_messageToRecord(t *testing.T) {
+	t.Parallel()
+
+	// reply subject format: $JS.ACK.<stream>.<consumer>.<delivered>.<sseq>.<cseq>.<tm>.<pending>
+	timestamp := time.Date(2022, 8, 30, 12, 0, 0, 0, time.UTC)
+	reply := "$JS.ACK.mystream.myconsumer.1.10.5." + strconv.FormatInt(timestamp.UnixNano(), 10) + ".0"
+
+	tests := []struct {
+		name         string
+		headerPrefix string
+		header       nats.Header
+		want         sdk.Metadata
+	}{
+		{
+			name:         "success, no headers",
+			headerPrefix: "nats.header.",
+			header:       nil,
+			want:         sdk.Metadata{},
+		},
+		{
+			name:         "success, single value headers",
+			headerPrefix: "nats.header.",
+			header: nats.Header{
+				"Nats-Msg-Id":  []string{"42"},
+				"Content-Type": []string{"application/json"},
+			},
+			want: sdk.Metadata{
+				"nats.header.Nats-Msg-Id":  "42",
+				"nats.header.Content-Type": "application/json",
+			},
+		},
+		{
+			name:         "success, multi-value header",
+			headerPrefix: "h.",
+			header: nats.Header{
+				"Trace": []string{"a", "b", "c"},
+			},
+			want: sdk.Metadata{
+				"h.Trace": "a,b,c",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			it := &Iterator{
+				headerPrefix: tt.headerPrefix,
+			}
+
+			record, err := it.messageToRecord(context.Background(), &nats.Msg{
+				Subject: "foo",
+				Reply:   reply,
+				Header:  tt.header,
+				Data:    []byte("something"),
+				Sub:     &nats.Subscription{},
+			})
+			if err != nil {
+				t.Fatalf("Iterator.messageToRecord() unexpected error = %v", err)
+			}
+
+			tt.want.SetCreatedAt(timestamp)
+			tt.want[MetadataCollection] = "foo"
+			tt.want[MetadataSubject] = "foo"
+			tt.want[MetadataStream] = "mystream"
+			tt.want[MetadataConsumer] = "myconsumer"
+			tt.want[MetadataStreamSequence] = "10"
+			tt.want[MetadataConsumerSequence] = "5"
+			tt.want[MetadataNumDelivered] = "1"
+			tt.want[MetadataNumPending] = "0"
+			// readAt is set to the current time, so we don't compare it
+			delete(record.Metadata, sdk.MetadataReadAt)
+
+			if !reflect.DeepEqual(record.Metadata, tt.want) {
+				t.Errorf("Iterator.messageToRecord() metadata = %v, want %v", record.Metadata, tt.want)
+			}
+		})
+	}
+}
+
+func TestIterator_Ack_alreadyAcknowledged(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		ackPolicy nats.AckPolicy
+		position  sdk.Position
+		wantErr   bool
+	}{
+		{
+			name:      "success, explicit ack policy",
+			ackPolicy: nats.AckExplicitPolicy,
+			position:  sdk.Position(`{"opt_seq":5}`),
+			wantErr:   false,
+		},
+		{
+			name:      "success, all ack policy",
+			ackPolicy: nats.AckAllPolicy,
+			position:  sdk.Position(`{"opt_seq":5}`),
+			wantErr:   false,
+		},
+		{
+			name:      "success, none ack policy",
+			ackPolicy: nats.AckNonePolicy,
+			position:  sdk.Position(`{"opt_seq":5}`),
+			wantErr:   false,
+		},
+		{
+			name:      "fail, invalid position",
+			ackPolicy: nats.AckExplicitPolicy,
+			position:  sdk.Position(`{"opt_seq":"5"}`),
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			it := &Iterator{
+				unackMessages: make(map[uint64]*nats.Msg),
+				consumerInfo: &nats.ConsumerInfo{
+					Config: nats.ConsumerConfig{AckPolicy: tt.ackPolicy},
+				},
+			}
+
+			if err := it.Ack(tt.position); (err != nil) != tt.wantErr {
+				t.Errorf("Iterator.Ack() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+const (
+	// MetadataCollection is a record metadata key for the name of a collection the record belongs to.
+	// The Iterator sets it to a subject of a message.
+	MetadataCollection = "opencdc.collection"
+	// MetadataSubject is a record metadata key for a subject of a message.
+	MetadataSubject = "nats.subject"
+	// MetadataStream is a record metadata key for a name of a stream the message was read from.
+	MetadataStream = "nats.stream"
+	// MetadataConsumer is a record metadata key for a name of a consumer the message was delivered by.
+	MetadataConsumer = "nats.consumer"
+	// MetadataStreamSequence is a record metadata key for a sequence number of a message in a stream.
+	MetadataStreamSequence = "nats.sequence.stream"
+	// MetadataConsumerSequence is a record metadata key for a sequence number of a message in a consumer.
+	MetadataConsumerSequence = "nats.sequence.consumer"
+	// MetadataNumDelivered is a record metadata key for a number of times the message was delivered.
+	MetadataNumDelivered = "nats.numDelivered"
+	// MetadataNumPending is a record metadata key for a number of messages
+	// pending in a consumer at the moment the message was delivered.
+	MetadataNumPending = "nats.numPending"
+)
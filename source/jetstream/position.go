@@ -16,19 +16,49 @@ package jetstream
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	sdk "github.com/conduitio/conduit-connector-sdk"
 )
 
+// positionVersion is the current version of the position format.
+// Positions without a version are the legacy {"opt_seq":N} ones, which hold a stream sequence only.
+const positionVersion = 1
+
+// ErrPositionMismatch is returned if a position belongs to a different stream or subject.
+var ErrPositionMismatch = errors.New("position mismatch")
+
 // position defines a position model for the JetStream iterator.
 type position struct {
+	// Version is a version of the position format.
+	Version int `json:"version"`
+	// Stream is a name of the stream the message was read from.
+	Stream string `json:"stream"`
+	// Subject is a filter subject of the consumer that delivered the message.
+	Subject string `json:"subject"`
+	// Consumer is a name of the consumer that delivered the message.
+	Consumer string `json:"consumer"`
+	// Sequence is a position of a message in a stream.
+	Sequence uint64 `json:"sequence"`
+	// Timestamp is the time the message was stored in the stream at,
+	// it's used to resume if the messages following the sequence were removed.
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	// EndTime is the end time of a time-range replay, it's set only if the replay is bounded.
+	EndTime *time.Time `json:"end_time,omitempty"`
+}
+
+// legacyPosition is a position stored before the position format was versioned.
+type legacyPosition struct {
 	// OptSeq is a position of a message in a stream.
 	OptSeq uint64 `json:"opt_seq"`
 }
 
 // marshalPosition marshals the underlying position into a sdk.Position as JSON bytes.
 func (p position) marshalSDKPosition() (sdk.Position, error) {
+	p.Version = positionVersion
+
 	positionBytes, err := json.Marshal(p)
 	if err != nil {
 		return nil, fmt.Errorf("marshal position: %w", err)
@@ -38,6 +68,7 @@ func (p position) marshalSDKPosition() (sdk.Position, error) {
 }
 
 // parsePosition converts an sdk.Position into a position.
+// Legacy positions are migrated to the current format, their stream, subject and consumer are empty.
 func parsePosition(sdkPosition sdk.Position) (position, error) {
 	var p position
 
@@ -49,5 +80,45 @@ func parsePosition(sdkPosition sdk.Position) (position, error) {
 		return position{}, fmt.Errorf("unmarshal sdk.Position into Position: %w", err)
 	}
 
-	return p, nil
+	switch p.Version {
+	case 0:
+		var legacy legacyPosition
+		if err := json.Unmarshal(sdkPosition, &legacy); err != nil {
+			return position{}, fmt.Errorf("unmarshal sdk.Position into legacy Position: %w", err)
+		}
+
+		return position{Sequence: legacy.OptSeq}, nil
+
+	case positionVersion:
+		return p, nil
+
+	default:
+		return position{}, fmt.Errorf("unsupported position version %d", p.Version)
+	}
+}
+
+// verify checks that the position belongs to the given stream and subject,
+// as its sequence is meaningless in other streams, and points to different messages for other subjects.
+// The migrated legacy positions cannot be verified, as they have no stream.
+func (p position) verify(stream, subject string) error {
+	if p.Version == 0 {
+		return nil
+	}
+
+	// positions of the other modes are versioned too, but have no stream
+	if p.Stream == "" {
+		return fmt.Errorf("%w: the position doesn't belong to a stream", ErrPositionMismatch)
+	}
+
+	if p.Stream != stream {
+		return fmt.Errorf("%w: the position belongs to the stream %q, but the stream is %q",
+			ErrPositionMismatch, p.Stream, stream)
+	}
+
+	if p.Subject != subject {
+		return fmt.Errorf("%w: the position belongs to the subject %q, but the subject is %q",
+			ErrPositionMismatch, p.Subject, subject)
+	}
+
+	return nil
 }
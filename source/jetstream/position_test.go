@@ -15,8 +15,10 @@
 package jetstream
 
 import (
+	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	sdk "github.com/conduitio/conduit-connector-sdk"
 )
@@ -24,6 +26,8 @@ import (
 func Test_position_marshalPosition(t *testing.T) {
 	t.Parallel()
 
+	endTime := time.Date(2022, 8, 30, 15, 30, 0, 0, time.UTC)
+
 	tests := []struct {
 		name    string
 		fields  position
@@ -33,10 +37,39 @@ func Test_position_marshalPosition(t *testing.T) {
 		{
 			name: "success, all fields",
 			fields: position{
-				OptSeq: 32,
+				Stream:   "orders",
+				Subject:  "orders.created",
+				Consumer: "conduit",
+				Sequence: 32,
+			},
+			want: sdk.Position(
+				`{"version":1,"stream":"orders","subject":"orders.created","consumer":"conduit","sequence":32}`,
+			),
+			wantErr: false,
+		},
+		{
+			name: "success, end time",
+			fields: position{
+				Stream:   "orders",
+				Sequence: 32,
+				EndTime:  &endTime,
+			},
+			want: sdk.Position(
+				`{"version":1,"stream":"orders","subject":"","consumer":"","sequence":32,` +
+					`"end_time":"2022-08-30T15:30:00Z"}`,
+			),
+			wantErr: false,
+		},
+		{
+			name: "success, timestamp",
+			fields: position{
+				Stream:    "orders",
+				Sequence:  32,
+				Timestamp: &endTime,
 			},
 			want: sdk.Position(
-				`{"opt_seq":32}`,
+				`{"version":1,"stream":"orders","subject":"","consumer":"","sequence":32,` +
+					`"timestamp":"2022-08-30T15:30:00Z"}`,
 			),
 			wantErr: false,
 		},
@@ -44,7 +77,7 @@ func Test_position_marshalPosition(t *testing.T) {
 			name:   "success, empty",
 			fields: position{},
 			want: sdk.Position(
-				`{"opt_seq":0}`,
+				`{"version":1,"stream":"","subject":"","consumer":"","sequence":0}`,
 			),
 			wantErr: false,
 		},
@@ -55,11 +88,7 @@ func Test_position_marshalPosition(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			p := position{
-				OptSeq: tt.fields.OptSeq,
-			}
-
-			got, err := p.marshalSDKPosition()
+			got, err := tt.fields.marshalSDKPosition()
 			if (err != nil) != tt.wantErr {
 				t.Errorf("position.marshalPosition() error = %v, wantErr %v", err, tt.wantErr)
 
@@ -76,6 +105,8 @@ func Test_position_marshalPosition(t *testing.T) {
 func Test_parsePosition(t *testing.T) {
 	t.Parallel()
 
+	endTime := time.Date(2022, 8, 30, 15, 30, 0, 0, time.UTC)
+
 	type args struct {
 		sdkPosition sdk.Position
 	}
@@ -87,13 +118,59 @@ func Test_parsePosition(t *testing.T) {
 	}{
 		{
 			name: "success, all fields",
+			args: args{
+				sdkPosition: sdk.Position([]byte(
+					`{"version":1,"stream":"orders","subject":"orders.created","consumer":"conduit","sequence":32}`,
+				)),
+			},
+			want: position{
+				Version:  1,
+				Stream:   "orders",
+				Subject:  "orders.created",
+				Consumer: "conduit",
+				Sequence: 32,
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, end time",
+			args: args{
+				sdkPosition: sdk.Position([]byte(
+					`{"version":1,"stream":"orders","sequence":32,"end_time":"2022-08-30T15:30:00Z"}`,
+				)),
+			},
+			want: position{
+				Version:  1,
+				Stream:   "orders",
+				Sequence: 32,
+				EndTime:  &endTime,
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, timestamp",
+			args: args{
+				sdkPosition: sdk.Position([]byte(
+					`{"version":1,"stream":"orders","sequence":32,"timestamp":"2022-08-30T15:30:00Z"}`,
+				)),
+			},
+			want: position{
+				Version:   1,
+				Stream:    "orders",
+				Sequence:  32,
+				Timestamp: &endTime,
+			},
+			wantErr: false,
+		},
+		{
+			name: "success, legacy position",
 			args: args{
 				sdkPosition: sdk.Position([]byte(
 					`{"opt_seq":32}`,
 				)),
 			},
 			want: position{
-				OptSeq: 32,
+				Sequence: 32,
 			},
 			wantErr: false,
 		},
@@ -105,7 +182,7 @@ func Test_parsePosition(t *testing.T) {
 				)),
 			},
 			want: position{
-				OptSeq: 0,
+				Sequence: 0,
 			},
 			wantErr: false,
 		},
@@ -115,7 +192,7 @@ func Test_parsePosition(t *testing.T) {
 				sdkPosition: sdk.Position(nil),
 			},
 			want: position{
-				OptSeq: 0,
+				Sequence: 0,
 			},
 			wantErr: false,
 		},
@@ -129,6 +206,16 @@ func Test_parsePosition(t *testing.T) {
 			want:    position{},
 			wantErr: true,
 		},
+		{
+			name: "fail, unsupported version",
+			args: args{
+				sdkPosition: sdk.Position([]byte(
+					`{"version":2,"stream":"orders","sequence":32}`,
+				)),
+			},
+			want:    position{},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -150,3 +237,68 @@ func Test_parsePosition(t *testing.T) {
 		})
 	}
 }
+
+func Test_position_verify(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		stream  string
+		subject string
+	}
+	tests := []struct {
+		name    string
+		fields  position
+		args    args
+		wantErr bool
+	}{
+		{
+			name:    "success, same stream and subject",
+			fields:  position{Version: 1, Stream: "orders", Subject: "orders.created", Consumer: "conduit", Sequence: 32},
+			args:    args{stream: "orders", subject: "orders.created"},
+			wantErr: false,
+		},
+		{
+			name:    "success, legacy position",
+			fields:  position{Sequence: 32},
+			args:    args{stream: "orders", subject: "orders.created"},
+			wantErr: false,
+		},
+		{
+			name:    "fail, position of another mode",
+			fields:  position{Version: 1, Sequence: 32},
+			args:    args{stream: "orders", subject: "orders.created"},
+			wantErr: true,
+		},
+		{
+			name:    "fail, different stream",
+			fields:  position{Version: 1, Stream: "payments", Subject: "orders.created", Sequence: 32},
+			args:    args{stream: "orders", subject: "orders.created"},
+			wantErr: true,
+		},
+		{
+			name:    "fail, different subject",
+			fields:  position{Version: 1, Stream: "orders", Subject: "orders.deleted", Sequence: 32},
+			args:    args{stream: "orders", subject: "orders.created"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.fields.verify(tt.args.stream, tt.args.subject)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("position.verify() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if err != nil && !errors.Is(err, ErrPositionMismatch) {
+				t.Errorf("position.verify() error = %v, want %v", err, ErrPositionMismatch)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/nats-io/nats.go"
+)
+
+// ErrSequencePurged is returned if messages following the position the Iterator resumes from
+// were removed from the stream and the PurgedPolicy is PurgedPolicyFail.
+var ErrSequencePurged = errors.New("resume sequence purged")
+
+// PurgedPolicy defines what the Iterator does if messages following the position it resumes from
+// were removed from the stream, e.g. by the retention limits or a purge.
+type PurgedPolicy int
+
+const (
+	// PurgedPolicyFail makes the Iterator return the ErrSequencePurged.
+	PurgedPolicyFail PurgedPolicy = iota
+	// PurgedPolicyFirstAvailable makes the Iterator resume from the first message in the stream.
+	PurgedPolicyFirstAvailable
+	// PurgedPolicyByTimestamp makes the Iterator resume from the first message
+	// not older than the message at the position.
+	PurgedPolicyByTimestamp
+)
+
+// resolvePurgedSequence checks that the message following the position is still in the stream.
+// If it was removed, the size of the gap is logged and the position is adjusted according to the policy.
+func resolvePurgedSequence(
+	ctx context.Context, jetstream nats.JetStreamContext, stream string, resumePosition position, policy PurgedPolicy,
+) (position, error) {
+	// the Iterator starts according to the deliver policy
+	if resumePosition.Sequence == 0 {
+		return resumePosition, nil
+	}
+
+	streamInfo, err := jetstream.StreamInfo(stream)
+	if err != nil {
+		return position{}, fmt.Errorf("get stream info: %w", err)
+	}
+
+	firstSequence := streamInfo.State.FirstSeq
+	if resumePosition.Sequence+1 >= firstSequence {
+		return resumePosition, nil
+	}
+
+	// the gap counts messages of all the subjects of the stream
+	gap := firstSequence - resumePosition.Sequence - 1
+
+	if policy == PurgedPolicyFail {
+		return position{}, fmt.Errorf("%w: %d messages following the sequence %d were removed, "+
+			"the first sequence of the stream %q is %d",
+			ErrSequencePurged, gap, resumePosition.Sequence, stream, firstSequence)
+	}
+
+	sdk.Logger(ctx).Warn().
+		Str("stream", stream).
+		Uint64("sequence", resumePosition.Sequence).
+		Uint64("first_sequence", firstSequence).
+		Uint64("gap", gap).
+		Msg("messages following the position were removed from the stream")
+
+	switch policy {
+	case PurgedPolicyFirstAvailable:
+		resumePosition.Sequence = firstSequence - 1
+
+	case PurgedPolicyByTimestamp:
+		if resumePosition.Timestamp == nil {
+			return position{}, fmt.Errorf("%w: the position has no timestamp to resume by", ErrSequencePurged)
+		}
+
+		// the message at the position was removed as well, so it isn't delivered again
+		resumePosition.Sequence = 0
+
+	default:
+		return position{}, fmt.Errorf("unknown purged policy %d", policy)
+	}
+
+	return resumePosition, nil
+}
@@ -0,0 +1,241 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/source/message"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/nats-io/nats.go"
+)
+
+// Iterator is an iterator for the Key-Value bucket communication model.
+// It watches the bucket and converts its entries into records.
+type Iterator struct {
+	conn     *nats.Conn
+	keyValue nats.KeyValue
+	watcher  nats.KeyWatcher
+	// resumeRevision is a revision of the last entry processed before the Iterator started,
+	// entries up to this revision are skipped
+	resumeRevision uint64
+	// keys holds keys which are known to exist in the bucket,
+	// it's used to tell created entries from updated ones
+	keys map[string]struct{}
+	// caughtUp is true once the watcher delivered the latest entries of all the keys
+	caughtUp bool
+	// errC receives async errors which interrupt waiting for entries
+	errC <-chan error
+}
+
+// IteratorParams contains incoming params for the NewIterator function.
+type IteratorParams struct {
+	Conn   *nats.Conn
+	Bucket string
+	// Keys is a pattern of keys to watch, it can contain wildcards.
+	Keys        string
+	SDKPosition sdk.Position
+	// ErrC is a channel of async errors, such as connection errors.
+	ErrC <-chan error
+}
+
+// NewIterator creates new instance of the Iterator.
+func NewIterator(params IteratorParams) (*Iterator, error) {
+	jetstream, err := params.Conn.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("get jetstream context: %w", err)
+	}
+
+	position, err := message.ParsePosition(params.SDKPosition, message.PositionModeKV)
+	if err != nil {
+		return nil, fmt.Errorf("parse position: %w", err)
+	}
+
+	keyValue, err := jetstream.KeyValue(params.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("get key value bucket %q: %w", params.Bucket, err)
+	}
+
+	// the watcher delivers the latest entry of each key first, and then all the further updates
+	watcher, err := keyValue.Watch(params.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("watch keys %q: %w", params.Keys, err)
+	}
+
+	return &Iterator{
+		conn:           params.Conn,
+		keyValue:       keyValue,
+		watcher:        watcher,
+		resumeRevision: position.Sequence,
+		keys:           make(map[string]struct{}),
+		errC:           params.ErrC,
+	}, nil
+}
+
+// Next returns the next record converted from a bucket entry.
+// It blocks until there is an entry, an async error occurs or the context is done.
+func (i *Iterator) Next(ctx context.Context) (sdk.Record, error) {
+	for {
+		entry, ok, err := message.Receive(ctx, i.watcher.Updates(), i.errC)
+		if err != nil {
+			return sdk.Record{}, err
+		}
+
+		if !ok {
+			return sdk.Record{}, errors.New("watcher is stopped")
+		}
+
+		// the watcher sends nil once all the initial entries are delivered
+		if entry == nil {
+			i.caughtUp = true
+
+			continue
+		}
+
+		// the entry was processed before the Iterator started,
+		// so it only marks whether the key exists
+		if entry.Revision() <= i.resumeRevision {
+			i.trackKey(entry)
+
+			continue
+		}
+
+		// the watcher delivers only the latest entry of a key changed while the connector was stopped,
+		// so the key history tells whether the key existed before
+		if !i.caughtUp && i.resumeRevision > 0 {
+			if err := i.lookUpKey(entry.Key()); err != nil {
+				return sdk.Record{}, fmt.Errorf("look up key: %w", err)
+			}
+		}
+
+		sdkRecord, err := i.entryToRecord(entry)
+		if err != nil {
+			return sdk.Record{}, fmt.Errorf("convert entry to record: %w", err)
+		}
+
+		i.trackKey(entry)
+
+		return sdkRecord, nil
+	}
+}
+
+// trackKey marks whether the entry's key exists in the bucket.
+func (i *Iterator) trackKey(entry nats.KeyValueEntry) {
+	if entry.Operation() == nats.KeyValuePut {
+		i.keys[entry.Key()] = struct{}{}
+
+		return
+	}
+
+	delete(i.keys, entry.Key())
+}
+
+// lookUpKey marks whether the key existed at the resume revision according to its history.
+// The history holds as many revisions of the key as the bucket keeps,
+// so a key without revisions up to the resume one is treated as a new one.
+func (i *Iterator) lookUpKey(key string) error {
+	entries, err := i.keyValue.History(key)
+	if err != nil {
+		if errors.Is(err, nats.ErrKeyNotFound) {
+			return nil
+		}
+
+		return fmt.Errorf("get history of key %q: %w", key, err)
+	}
+
+	for _, entry := range entries {
+		if entry.Revision() > i.resumeRevision {
+			break
+		}
+
+		i.trackKey(entry)
+	}
+
+	return nil
+}
+
+// Ack does nothing, as entries are delivered by an ordered consumer which doesn't need acknowledgements.
+// The position of the last processed entry is used to resume watching the bucket.
+func (i *Iterator) Ack(sdk.Position) error {
+	return nil
+}
+
+// Stop stops watching the bucket and closes the connection.
+func (i *Iterator) Stop() error {
+	if i.watcher != nil {
+		if err := i.watcher.Stop(); err != nil {
+			return fmt.Errorf("stop watcher: %w", err)
+		}
+	}
+
+	if i.conn != nil {
+		i.conn.Close()
+	}
+
+	return nil
+}
+
+// entryToRecord converts a nats.KeyValueEntry to a sdk.Record.
+// A put becomes a create record if the key isn't known to exist, and an update record otherwise.
+// Deletes and purges become delete records.
+func (i *Iterator) entryToRecord(entry nats.KeyValueEntry) (sdk.Record, error) {
+	position, err := message.Position{Mode: message.PositionModeKV, Sequence: entry.Revision()}.MarshalSDKPosition()
+	if err != nil {
+		return sdk.Record{}, fmt.Errorf("marshal position: %w", err)
+	}
+
+	metadata := sdk.Metadata{
+		MetadataCollection: entry.Bucket(),
+		MetadataBucket:     entry.Bucket(),
+		MetadataKey:        entry.Key(),
+		MetadataRevision:   strconv.FormatUint(entry.Revision(), 10),
+		MetadataOperation:  operationName(entry.Operation()),
+	}
+	metadata.SetCreatedAt(entry.Created())
+
+	key := sdk.RawData(entry.Key())
+
+	switch entry.Operation() {
+	case nats.KeyValuePut:
+		if _, ok := i.keys[entry.Key()]; ok {
+			return sdk.Util.Source.NewRecordUpdate(position, metadata, key, nil, sdk.RawData(entry.Value())), nil
+		}
+
+		return sdk.Util.Source.NewRecordCreate(position, metadata, key, sdk.RawData(entry.Value())), nil
+
+	case nats.KeyValueDelete, nats.KeyValuePurge:
+		return sdk.Util.Source.NewRecordDelete(position, metadata, key), nil
+
+	default:
+		return sdk.Record{}, fmt.Errorf("unknown operation %q", entry.Operation())
+	}
+}
+
+// operationName returns a short lowercase name of a Key-Value operation.
+func operationName(op nats.KeyValueOp) string {
+	switch op {
+	case nats.KeyValuePut:
+		return "put"
+	case nats.KeyValueDelete:
+		return "delete"
+	case nats.KeyValuePurge:
+		return "purge"
+	default:
+		return op.String()
+	}
+}
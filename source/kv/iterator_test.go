@@ -0,0 +1,225 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/nats-io/nats.go"
+)
+
+// testEntry is a nats.KeyValueEntry with fixed values.
+type testEntry struct {
+	key       string
+	value     []byte
+	revision  uint64
+	operation nats.KeyValueOp
+}
+
+func (e testEntry) Bucket() string             { return "settings" }
+func (e testEntry) Key() string                { return e.key }
+func (e testEntry) Value() []byte              { return e.value }
+func (e testEntry) Revision() uint64           { return e.revision }
+func (e testEntry) Created() time.Time         { return time.Unix(1661860800, 0) }
+func (e testEntry) Delta() uint64              { return 0 }
+func (e testEntry) Operation() nats.KeyValueOp { return e.operation }
+
+// testWatcher is a nats.KeyWatcher which delivers the given entries.
+type testWatcher struct {
+	updates chan nats.KeyValueEntry
+}
+
+func newTestWatcher(entries ...nats.KeyValueEntry) *testWatcher {
+	updates := make(chan nats.KeyValueEntry, len(entries))
+	for _, entry := range entries {
+		updates <- entry
+	}
+
+	return &testWatcher{updates: updates}
+}
+
+func (w *testWatcher) Context() context.Context           { return context.Background() }
+func (w *testWatcher) Updates() <-chan nats.KeyValueEntry { return w.updates }
+func (w *testWatcher) Stop() error                        { return nil }
+
+// testKeyValue is a nats.KeyValue which returns the given histories of keys.
+type testKeyValue struct {
+	nats.KeyValue
+	history map[string][]nats.KeyValueEntry
+}
+
+func (kv testKeyValue) History(key string, _ ...nats.WatchOpt) ([]nats.KeyValueEntry, error) {
+	entries, ok := kv.history[key]
+	if !ok {
+		return nil, nats.ErrKeyNotFound
+	}
+
+	return entries, nil
+}
+
+func TestIterator_Next(t *testing.T) {
+	t.Parallel()
+
+	it := &Iterator{
+		watcher: newTestWatcher(
+			// processed before the iterator started
+			testEntry{key: "a", value: []byte("1"), revision: 1, operation: nats.KeyValuePut},
+			testEntry{key: "b", value: []byte("1"), revision: 3, operation: nats.KeyValuePut},
+			// the end of the initial entries
+			nil,
+			testEntry{key: "a", value: []byte("2"), revision: 4, operation: nats.KeyValuePut},
+			testEntry{key: "a", revision: 5, operation: nats.KeyValueDelete},
+			testEntry{key: "a", value: []byte("3"), revision: 6, operation: nats.KeyValuePut},
+			testEntry{key: "b", revision: 7, operation: nats.KeyValuePurge},
+		),
+		resumeRevision: 3,
+		keys:           make(map[string]struct{}),
+	}
+
+	want := []struct {
+		operation sdk.Operation
+		key       string
+		position  string
+	}{
+		{operation: sdk.OperationUpdate, key: "a", position: `{"version":1,"mode":"kv","sequence":4}`},
+		{operation: sdk.OperationDelete, key: "a", position: `{"version":1,"mode":"kv","sequence":5}`},
+		{operation: sdk.OperationCreate, key: "a", position: `{"version":1,"mode":"kv","sequence":6}`},
+		{operation: sdk.OperationDelete, key: "b", position: `{"version":1,"mode":"kv","sequence":7}`},
+	}
+
+	for _, w := range want {
+		record, err := it.Next(context.Background())
+		if err != nil {
+			t.Fatalf("Iterator.Next() error = %v", err)
+		}
+
+		if record.Operation != w.operation {
+			t.Errorf("Iterator.Next() operation = %v, want %v", record.Operation, w.operation)
+		}
+
+		if string(record.Key.Bytes()) != w.key {
+			t.Errorf("Iterator.Next() key = %s, want %s", record.Key.Bytes(), w.key)
+		}
+
+		if string(record.Position) != w.position {
+			t.Errorf("Iterator.Next() position = %s, want %s", record.Position, w.position)
+		}
+	}
+}
+
+func TestIterator_Next_changedWhileStopped(t *testing.T) {
+	t.Parallel()
+
+	it := &Iterator{
+		keyValue: testKeyValue{
+			history: map[string][]nats.KeyValueEntry{
+				"a": {
+					testEntry{key: "a", value: []byte("1"), revision: 1, operation: nats.KeyValuePut},
+					testEntry{key: "a", value: []byte("2"), revision: 5, operation: nats.KeyValuePut},
+				},
+				"b": {
+					testEntry{key: "b", value: []byte("1"), revision: 6, operation: nats.KeyValuePut},
+				},
+				"c": {
+					testEntry{key: "c", value: []byte("1"), revision: 2, operation: nats.KeyValuePut},
+					testEntry{key: "c", revision: 3, operation: nats.KeyValueDelete},
+					testEntry{key: "c", value: []byte("2"), revision: 7, operation: nats.KeyValuePut},
+				},
+			},
+		},
+		watcher: newTestWatcher(
+			// the latest entries of the keys changed while the connector was stopped
+			testEntry{key: "a", value: []byte("2"), revision: 5, operation: nats.KeyValuePut},
+			testEntry{key: "b", value: []byte("1"), revision: 6, operation: nats.KeyValuePut},
+			testEntry{key: "c", value: []byte("2"), revision: 7, operation: nats.KeyValuePut},
+			// the end of the initial entries
+			nil,
+			testEntry{key: "d", value: []byte("1"), revision: 8, operation: nats.KeyValuePut},
+		),
+		resumeRevision: 4,
+		keys:           make(map[string]struct{}),
+	}
+
+	want := []struct {
+		operation sdk.Operation
+		key       string
+	}{
+		// updated after the resume revision
+		{operation: sdk.OperationUpdate, key: "a"},
+		// created after the resume revision
+		{operation: sdk.OperationCreate, key: "b"},
+		// deleted before the resume revision and created again
+		{operation: sdk.OperationCreate, key: "c"},
+		// created after the watcher caught up, its history isn't looked up
+		{operation: sdk.OperationCreate, key: "d"},
+	}
+
+	for _, w := range want {
+		record, err := it.Next(context.Background())
+		if err != nil {
+			t.Fatalf("Iterator.Next() error = %v", err)
+		}
+
+		if record.Operation != w.operation {
+			t.Errorf("Iterator.Next() operation of key %s = %v, want %v", w.key, record.Operation, w.operation)
+		}
+
+		if string(record.Key.Bytes()) != w.key {
+			t.Errorf("Iterator.Next() key = %s, want %s", record.Key.Bytes(), w.key)
+		}
+	}
+}
+
+func TestIterator_entryToRecord(t *testing.T) {
+	t.Parallel()
+
+	it := &Iterator{keys: make(map[string]struct{})}
+
+	record, err := it.entryToRecord(testEntry{
+		key:       "services.api.config",
+		value:     []byte(`{"replicas":3}`),
+		revision:  10,
+		operation: nats.KeyValuePut,
+	})
+	if err != nil {
+		t.Fatalf("Iterator.entryToRecord() error = %v", err)
+	}
+
+	wantMetadata := sdk.Metadata{
+		MetadataCollection: "settings",
+		MetadataBucket:     "settings",
+		MetadataKey:        "services.api.config",
+		MetadataRevision:   "10",
+		MetadataOperation:  "put",
+	}
+	wantMetadata.SetCreatedAt(time.Unix(1661860800, 0))
+
+	for key, value := range wantMetadata {
+		if record.Metadata[key] != value {
+			t.Errorf("Iterator.entryToRecord() metadata[%q] = %q, want %q", key, record.Metadata[key], value)
+		}
+	}
+
+	if record.Operation != sdk.OperationCreate {
+		t.Errorf("Iterator.entryToRecord() operation = %v, want %v", record.Operation, sdk.OperationCreate)
+	}
+
+	if string(record.Payload.After.Bytes()) != `{"replicas":3}` {
+		t.Errorf("Iterator.entryToRecord() payload = %s, want %s", record.Payload.After.Bytes(), `{"replicas":3}`)
+	}
+}
@@ -0,0 +1,29 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+const (
+	// MetadataCollection is a record metadata key for the name of a collection the record belongs to.
+	// The Iterator sets it to a name of a bucket.
+	MetadataCollection = "opencdc.collection"
+	// MetadataBucket is a record metadata key for a name of a bucket the entry was read from.
+	MetadataBucket = "nats.kv.bucket"
+	// MetadataKey is a record metadata key for a key of an entry.
+	MetadataKey = "nats.kv.key"
+	// MetadataRevision is a record metadata key for a revision of an entry.
+	MetadataRevision = "nats.kv.revision"
+	// MetadataOperation is a record metadata key for an operation of an entry, such as put, delete or purge.
+	MetadataOperation = "nats.kv.operation"
+)
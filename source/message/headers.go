@@ -0,0 +1,30 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"strings"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/nats-io/nats.go"
+)
+
+// SetHeaders copies the headers into the sdk.Metadata, prefixing their names with the prefix.
+// Multi-value headers are joined together with a comma.
+func SetHeaders(metadata sdk.Metadata, header nats.Header, prefix string) {
+	for name, values := range header {
+		metadata[prefix+name] = strings.Join(values, ",")
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/nats-io/nats.go"
+)
+
+// KeySourceType defines where the Iterator takes a record key from.
+type KeySourceType int
+
+const (
+	// KeySourceHeader takes a record key from a message header.
+	KeySourceHeader KeySourceType = iota
+	// KeySourceSubject takes a record key from a message subject token.
+	KeySourceSubject
+	// KeySourcePayload takes a record key from a field of a JSON message payload.
+	KeySourcePayload
+)
+
+// KeySource describes how an iterator extracts a record key from a message.
+type KeySource struct {
+	Type KeySourceType
+	// Header is a name of a header that holds a key.
+	Header string
+	// SubjectToken is a zero-based index of a subject token that holds a key.
+	SubjectToken int
+	// PayloadPath is a path to a payload field that holds a key,
+	// each element is either an object field name or an array index.
+	PayloadPath []string
+}
+
+// ExtractKey returns a record key for the given message.
+// If the message doesn't contain a value the KeySource points to, the key is nil.
+// A payload which is not a valid JSON has no value at any path either,
+// and a warning is logged in both cases, so that such messages are still read.
+func (k KeySource) ExtractKey(ctx context.Context, msg *nats.Msg) (sdk.Data, error) {
+	switch k.Type {
+	case KeySourceHeader:
+		value := msg.Header.Get(k.Header)
+		if value == "" {
+			return nil, nil
+		}
+
+		return sdk.RawData(value), nil
+
+	case KeySourceSubject:
+		tokens := strings.Split(msg.Subject, ".")
+		if k.SubjectToken >= len(tokens) {
+			return nil, nil
+		}
+
+		return sdk.RawData(tokens[k.SubjectToken]), nil
+
+	case KeySourcePayload:
+		value, err := lookupJSONPath(msg.Data, k.PayloadPath)
+		if err != nil || value == nil {
+			sdk.Logger(ctx).Warn().Err(err).
+				Str("payload_path", strings.Join(k.PayloadPath, ".")).
+				Msg("payload has no value at the key path, the record key is nil")
+
+			return nil, nil
+		}
+
+		// keep string values as they are, other values are represented in JSON
+		var str string
+		if err := json.Unmarshal(value, &str); err == nil {
+			return sdk.RawData(str), nil
+		}
+
+		return sdk.RawData(value), nil
+
+	default:
+		return nil, fmt.Errorf("unknown key source type %d", k.Type)
+	}
+}
+
+// lookupJSONPath returns a raw JSON value located at the given path within the data.
+// It returns nil if there's no value at the path.
+func lookupJSONPath(data []byte, path []string) (json.RawMessage, error) {
+	value := json.RawMessage(data)
+
+	for _, element := range path {
+		switch {
+		case isJSONObject(value):
+			var object map[string]json.RawMessage
+			if err := json.Unmarshal(value, &object); err != nil {
+				return nil, fmt.Errorf("unmarshal object: %w", err)
+			}
+
+			value = object[element]
+
+		case isJSONArray(value):
+			var array []json.RawMessage
+			if err := json.Unmarshal(value, &array); err != nil {
+				return nil, fmt.Errorf("unmarshal array: %w", err)
+			}
+
+			index, err := strconv.Atoi(element)
+			if err != nil || index < 0 || index >= len(array) {
+				return nil, nil
+			}
+
+			value = array[index]
+
+		default:
+			// there's no way to go deeper into a scalar value
+			if !json.Valid(value) {
+				return nil, fmt.Errorf("payload is not a valid JSON")
+			}
+
+			return nil, nil
+		}
+
+		if value == nil {
+			return nil, nil
+		}
+	}
+
+	if string(value) == "null" {
+		return nil, nil
+	}
+
+	return value, nil
+}
+
+// isJSONObject checks if the raw JSON value is an object.
+func isJSONObject(value json.RawMessage) bool {
+	trimmed := strings.TrimSpace(string(value))
+
+	return strings.HasPrefix(trimmed, "{")
+}
+
+// isJSONArray checks if the raw JSON value is an array.
+func isJSONArray(value json.RawMessage) bool {
+	trimmed := strings.TrimSpace(string(value))
+
+	return strings.HasPrefix(trimmed, "[")
+}
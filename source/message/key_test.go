@@ -0,0 +1,142 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/nats-io/nats.go"
+)
+
+func TestKeySource_ExtractKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		keySource KeySource
+		msg       *nats.Msg
+		want      sdk.Data
+		wantErr   bool
+	}{
+		{
+			name:      "success, header",
+			keySource: KeySource{Type: KeySourceHeader, Header: nats.MsgIdHdr},
+			msg: &nats.Msg{
+				Header: nats.Header{nats.MsgIdHdr: []string{"42"}},
+			},
+			want: sdk.RawData("42"),
+		},
+		{
+			name:      "success, missing header",
+			keySource: KeySource{Type: KeySourceHeader, Header: nats.MsgIdHdr},
+			msg:       &nats.Msg{},
+			want:      nil,
+		},
+		{
+			name:      "success, subject token",
+			keySource: KeySource{Type: KeySourceSubject, SubjectToken: 1},
+			msg:       &nats.Msg{Subject: "orders.eu.created"},
+			want:      sdk.RawData("eu"),
+		},
+		{
+			name:      "success, subject token out of range",
+			keySource: KeySource{Type: KeySourceSubject, SubjectToken: 3},
+			msg:       &nats.Msg{Subject: "orders.eu.created"},
+			want:      nil,
+		},
+		{
+			name:      "success, payload string field",
+			keySource: KeySource{Type: KeySourcePayload, PayloadPath: []string{"user", "id"}},
+			msg:       &nats.Msg{Data: []byte(`{"user": {"id": "abc"}}`)},
+			want:      sdk.RawData("abc"),
+		},
+		{
+			name:      "success, payload number field",
+			keySource: KeySource{Type: KeySourcePayload, PayloadPath: []string{"id"}},
+			msg:       &nats.Msg{Data: []byte(`{"id": 42}`)},
+			want:      sdk.RawData("42"),
+		},
+		{
+			name:      "success, payload array element",
+			keySource: KeySource{Type: KeySourcePayload, PayloadPath: []string{"ids", "1"}},
+			msg:       &nats.Msg{Data: []byte(`{"ids": ["a", "b"]}`)},
+			want:      sdk.RawData("b"),
+		},
+		{
+			name:      "success, payload object field",
+			keySource: KeySource{Type: KeySourcePayload, PayloadPath: []string{"pk"}},
+			msg:       &nats.Msg{Data: []byte(`{"pk": {"a": 1}}`)},
+			want:      sdk.RawData(`{"a": 1}`),
+		},
+		{
+			name:      "success, missing payload field",
+			keySource: KeySource{Type: KeySourcePayload, PayloadPath: []string{"user", "name"}},
+			msg:       &nats.Msg{Data: []byte(`{"user": {"id": "abc"}}`)},
+			want:      nil,
+		},
+		{
+			name:      "success, null payload field",
+			keySource: KeySource{Type: KeySourcePayload, PayloadPath: []string{"id"}},
+			msg:       &nats.Msg{Data: []byte(`{"id": null}`)},
+			want:      nil,
+		},
+		{
+			name:      "success, payload is a JSON scalar",
+			keySource: KeySource{Type: KeySourcePayload, PayloadPath: []string{"id"}},
+			msg:       &nats.Msg{Data: []byte(`42`)},
+			want:      nil,
+		},
+		{
+			name:      "success, payload is not a JSON",
+			keySource: KeySource{Type: KeySourcePayload, PayloadPath: []string{"id"}},
+			msg:       &nats.Msg{Data: []byte(`something`)},
+			want:      nil,
+		},
+		{
+			name:      "success, payload is a malformed JSON object",
+			keySource: KeySource{Type: KeySourcePayload, PayloadPath: []string{"id"}},
+			msg:       &nats.Msg{Data: []byte(`{"id": `)},
+			want:      nil,
+		},
+		{
+			name:      "fail, unknown key source type",
+			keySource: KeySource{Type: KeySourceType(42)},
+			msg:       &nats.Msg{},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.keySource.ExtractKey(context.Background(), tt.msg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("KeySource.ExtractKey() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("KeySource.ExtractKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
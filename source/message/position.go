@@ -0,0 +1,110 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// positionVersion is the current version of the position format.
+// Positions without a version are the legacy ones, which hold a sequence or a revision only.
+const positionVersion = 1
+
+const (
+	// PositionModeKV is a mode of positions of the Key-Value iterator.
+	PositionModeKV = "kv"
+	// PositionModeObject is a mode of positions of the Object Store iterator.
+	PositionModeObject = "object"
+	// PositionModeCore is a mode of positions of the core NATS iterator.
+	PositionModeCore = "core"
+)
+
+// ErrPositionMismatch is returned if a position belongs to a different mode.
+var ErrPositionMismatch = errors.New("position mismatch")
+
+// Position defines a position model shared by the Key-Value, Object Store and core NATS iterators.
+type Position struct {
+	// Version is a version of the position format.
+	Version int `json:"version"`
+	// Mode is a mode of the iterator the position belongs to,
+	// as the sequence means a different thing in each mode.
+	Mode string `json:"mode"`
+	// Sequence is a revision of a Key-Value entry, a sequence of an object metadata message in a bucket stream,
+	// or a number of a core NATS message received by the connector.
+	Sequence uint64 `json:"sequence"`
+}
+
+// legacyPosition is a position stored before the position format was versioned.
+type legacyPosition struct {
+	// Sequence is a sequence of the Object Store and core NATS positions.
+	Sequence uint64 `json:"sequence"`
+	// Revision is a revision of the Key-Value positions.
+	Revision uint64 `json:"revision"`
+}
+
+// MarshalSDKPosition marshals the underlying position into a sdk.Position as JSON bytes.
+func (p Position) MarshalSDKPosition() (sdk.Position, error) {
+	p.Version = positionVersion
+
+	positionBytes, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("marshal position: %w", err)
+	}
+
+	return sdk.Position(positionBytes), nil
+}
+
+// ParsePosition converts an sdk.Position into a Position and checks that it belongs to the given mode.
+// Legacy positions are migrated to the current format, as their mode cannot be verified.
+func ParsePosition(sdkPosition sdk.Position, mode string) (Position, error) {
+	var p Position
+
+	if sdkPosition == nil {
+		return Position{Mode: mode}, nil
+	}
+
+	if err := json.Unmarshal(sdkPosition, &p); err != nil {
+		return Position{}, fmt.Errorf("unmarshal sdk.Position into Position: %w", err)
+	}
+
+	switch p.Version {
+	case 0:
+		var legacy legacyPosition
+		if err := json.Unmarshal(sdkPosition, &legacy); err != nil {
+			return Position{}, fmt.Errorf("unmarshal sdk.Position into legacy Position: %w", err)
+		}
+
+		if mode == PositionModeKV {
+			return Position{Mode: mode, Sequence: legacy.Revision}, nil
+		}
+
+		return Position{Mode: mode, Sequence: legacy.Sequence}, nil
+
+	case positionVersion:
+		if p.Mode != mode {
+			return Position{}, fmt.Errorf("%w: the position belongs to the %q mode, but the mode is %q",
+				ErrPositionMismatch, p.Mode, mode)
+		}
+
+		return p, nil
+
+	default:
+		return Position{}, fmt.Errorf("unsupported position version %d", p.Version)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+func TestParsePosition(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		sdkPosition  sdk.Position
+		mode         string
+		want         Position
+		wantErr      bool
+		wantMismatch bool
+	}{
+		{
+			name:        "success, kv position",
+			sdkPosition: sdk.Position(`{"version":1,"mode":"kv","sequence":32}`),
+			mode:        PositionModeKV,
+			want:        Position{Version: 1, Mode: PositionModeKV, Sequence: 32},
+		},
+		{
+			name:        "success, object position",
+			sdkPosition: sdk.Position(`{"version":1,"mode":"object","sequence":32}`),
+			mode:        PositionModeObject,
+			want:        Position{Version: 1, Mode: PositionModeObject, Sequence: 32},
+		},
+		{
+			name:        "success, core position",
+			sdkPosition: sdk.Position(`{"version":1,"mode":"core","sequence":32}`),
+			mode:        PositionModeCore,
+			want:        Position{Version: 1, Mode: PositionModeCore, Sequence: 32},
+		},
+		{
+			name:        "success, position is nil",
+			sdkPosition: nil,
+			mode:        PositionModeKV,
+			want:        Position{Mode: PositionModeKV},
+		},
+		{
+			name:        "success, legacy kv position",
+			sdkPosition: sdk.Position(`{"revision":32}`),
+			mode:        PositionModeKV,
+			want:        Position{Mode: PositionModeKV, Sequence: 32},
+		},
+		{
+			name:        "success, legacy object position",
+			sdkPosition: sdk.Position(`{"sequence":32}`),
+			mode:        PositionModeObject,
+			want:        Position{Mode: PositionModeObject, Sequence: 32},
+		},
+		{
+			name:        "success, legacy core position",
+			sdkPosition: sdk.Position(`{"sequence":32}`),
+			mode:        PositionModeCore,
+			want:        Position{Mode: PositionModeCore, Sequence: 32},
+		},
+		{
+			name:         "fail, position of another mode",
+			sdkPosition:  sdk.Position(`{"version":1,"mode":"object","sequence":32}`),
+			mode:         PositionModeKV,
+			wantErr:      true,
+			wantMismatch: true,
+		},
+		{
+			name:         "fail, jetstream position",
+			sdkPosition:  sdk.Position(`{"version":1,"stream":"orders","subject":"orders","sequence":32}`),
+			mode:         PositionModeCore,
+			wantErr:      true,
+			wantMismatch: true,
+		},
+		{
+			name:        "fail, unsupported version",
+			sdkPosition: sdk.Position(`{"version":2,"mode":"kv","sequence":32}`),
+			mode:        PositionModeKV,
+			wantErr:     true,
+		},
+		{
+			name:        "fail, wrong field type",
+			sdkPosition: sdk.Position(`{"version":1,"mode":"kv","sequence":"32"}`),
+			mode:        PositionModeKV,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParsePosition(tt.sdkPosition, tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePosition() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if errors.Is(err, ErrPositionMismatch) != tt.wantMismatch {
+				t.Errorf("ParsePosition() error = %v, wantMismatch %v", err, tt.wantMismatch)
+
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePosition() = %v, want %v", got, tt.want)
+
+				return
+			}
+
+			// a parsed position is marshaled into the current format, which parses back into the same position
+			sdkPosition, err := got.MarshalSDKPosition()
+			if err != nil {
+				t.Errorf("Position.MarshalSDKPosition() error = %v", err)
+
+				return
+			}
+
+			reparsed, err := ParsePosition(sdkPosition, tt.mode)
+			if err != nil {
+				t.Errorf("ParsePosition() of a marshaled position error = %v", err)
+
+				return
+			}
+
+			if reparsed.Mode != got.Mode || reparsed.Sequence != got.Sequence || reparsed.Version != positionVersion {
+				t.Errorf("ParsePosition() of a marshaled position = %v, want %v", reparsed, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package message contains helpers shared by the iterators of all the source modes.
+package message
+
+import (
+	"context"
+	"fmt"
+)
+
+// Receive waits for the next value from the values channel, an async error, or the context to be done.
+// Already buffered values are returned even if the context is done,
+// so they're still delivered during a graceful stop.
+// The ok result is false if the values channel is closed.
+func Receive[T any](ctx context.Context, values <-chan T, errC <-chan error) (value T, ok bool, err error) {
+	select {
+	case err := <-errC:
+		return value, false, fmt.Errorf("got an async error: %w", err)
+
+	case value, ok := <-values:
+		return value, ok, nil
+
+	default:
+	}
+
+	select {
+	case err := <-errC:
+		return value, false, fmt.Errorf("got an async error: %w", err)
+
+	case value, ok := <-values:
+		return value, ok, nil
+
+	case <-ctx.Done():
+		return value, false, ctx.Err()
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestReceive(t *testing.T) {
+	t.Parallel()
+
+	msg := &nats.Msg{Subject: "foo", Data: []byte("something")}
+	asyncErr := errors.New("slow consumer")
+
+	tests := []struct {
+		name      string
+		buffered  *nats.Msg
+		asyncErr  error
+		cancelCtx bool
+		closed    bool
+		want      *nats.Msg
+		wantOK    bool
+		wantErr   error
+	}{
+		{
+			name:     "success, buffered message",
+			buffered: msg,
+			want:     msg,
+			wantOK:   true,
+		},
+		{
+			name:      "success, buffered message, context is done",
+			buffered:  msg,
+			cancelCtx: true,
+			want:      msg,
+			wantOK:    true,
+		},
+		{
+			name:   "success, closed channel",
+			closed: true,
+			want:   nil,
+			wantOK: false,
+		},
+		{
+			name:     "fail, async error",
+			asyncErr: asyncErr,
+			wantErr:  asyncErr,
+		},
+		{
+			name:      "fail, no messages, context is done",
+			cancelCtx: true,
+			wantErr:   context.Canceled,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			messages, errC := make(chan *nats.Msg, 1), make(chan error, 1)
+			if tt.buffered != nil {
+				messages <- tt.buffered
+			}
+
+			if tt.closed {
+				close(messages)
+			}
+
+			if tt.asyncErr != nil {
+				errC <- tt.asyncErr
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancelCtx {
+				cancel()
+			}
+			defer cancel()
+
+			got, ok, err := Receive(ctx, messages, errC)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Receive() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Receive() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
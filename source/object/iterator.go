@@ -0,0 +1,290 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/source/message"
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// streamNameTemplate is a template of a name of a stream which backs an Object Store bucket.
+	streamNameTemplate = "OBJ_%s"
+	// metaSubjectTemplate is a template of a subject of object metadata messages of a bucket.
+	metaSubjectTemplate = "$O.%s.M.>"
+)
+
+// Iterator is an iterator for the Object Store communication model.
+// It watches metadata of objects in the bucket and converts them along with their contents into records.
+type Iterator struct {
+	conn         *nats.Conn
+	objectStore  nats.ObjectStore
+	messages     chan *nats.Msg
+	subscription *nats.Subscription
+	// resumeSequence is a sequence of the last metadata message processed before the Iterator started,
+	// messages up to this sequence are skipped
+	resumeSequence uint64
+	// maxContentSize is the maximum size of an object whose contents are put into a record,
+	// records of larger objects contain references to them
+	maxContentSize uint64
+	// headerPrefix is prepended to object header names
+	// when they're copied into a record's metadata.
+	headerPrefix string
+	// names holds names of objects which are known to exist in the bucket,
+	// it's used to tell created objects from updated ones
+	names map[string]struct{}
+	// errC receives async errors which interrupt waiting for objects
+	errC <-chan error
+}
+
+// IteratorParams contains incoming params for the NewIterator function.
+type IteratorParams struct {
+	Conn           *nats.Conn
+	Bucket         string
+	BufferSize     int
+	SDKPosition    sdk.Position
+	MaxContentSize uint64
+	HeaderPrefix   string
+	// ErrC is a channel of async errors, such as connection errors.
+	ErrC <-chan error
+}
+
+// NewIterator creates new instance of the Iterator.
+func NewIterator(params IteratorParams) (*Iterator, error) {
+	jetstream, err := params.Conn.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("get jetstream context: %w", err)
+	}
+
+	position, err := message.ParsePosition(params.SDKPosition, message.PositionModeObject)
+	if err != nil {
+		return nil, fmt.Errorf("parse position: %w", err)
+	}
+
+	objectStore, err := jetstream.ObjectStore(params.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("get object store bucket %q: %w", params.Bucket, err)
+	}
+
+	// the ordered consumer delivers the latest metadata of each object first, and then all the further updates,
+	// the Iterator subscribes to them on its own instead of using a watcher to get their stream sequences
+	messages := make(chan *nats.Msg, params.BufferSize)
+
+	subscription, err := jetstream.ChanSubscribe(
+		fmt.Sprintf(metaSubjectTemplate, params.Bucket),
+		messages,
+		nats.BindStream(fmt.Sprintf(streamNameTemplate, params.Bucket)),
+		nats.OrderedConsumer(),
+		nats.DeliverLastPerSubject(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("chan subscribe: %w", err)
+	}
+
+	return &Iterator{
+		conn:           params.Conn,
+		objectStore:    objectStore,
+		messages:       messages,
+		subscription:   subscription,
+		resumeSequence: position.Sequence,
+		maxContentSize: params.MaxContentSize,
+		headerPrefix:   params.HeaderPrefix,
+		names:          make(map[string]struct{}),
+		errC:           params.ErrC,
+	}, nil
+}
+
+// Next returns the next record converted from an object.
+// It blocks until there is an object, an async error occurs or the context is done.
+func (i *Iterator) Next(ctx context.Context) (sdk.Record, error) {
+	for {
+		msg, _, err := message.Receive(ctx, i.messages, i.errC)
+		if err != nil {
+			return sdk.Record{}, err
+		}
+
+		msgMetadata, err := msg.Metadata()
+		if err != nil {
+			return sdk.Record{}, fmt.Errorf("get message metadata: %w", err)
+		}
+
+		var info nats.ObjectInfo
+		if err := json.Unmarshal(msg.Data, &info); err != nil {
+			return sdk.Record{}, fmt.Errorf("unmarshal object info: %w", err)
+		}
+
+		info.ModTime = msgMetadata.Timestamp
+
+		// the object was processed before the Iterator started,
+		// so it only marks whether the object exists
+		if msgMetadata.Sequence.Stream <= i.resumeSequence {
+			i.trackName(&info)
+
+			continue
+		}
+
+		content, err := i.getContent(&info)
+		if err != nil {
+			return sdk.Record{}, fmt.Errorf("get content of object %q: %w", info.Name, err)
+		}
+
+		// the object was deleted or replaced after the metadata message was sent,
+		// so there is a newer metadata message which will be processed later
+		if content == nil && !info.Deleted {
+			continue
+		}
+
+		sdkRecord, err := i.infoToRecord(msgMetadata.Sequence.Stream, &info, content)
+		if err != nil {
+			return sdk.Record{}, fmt.Errorf("convert object info to record: %w", err)
+		}
+
+		i.trackName(&info)
+
+		return sdkRecord, nil
+	}
+}
+
+// getContent returns the contents of an object, or a reference to it if the object is too large.
+// It returns nil if the object is deleted, or if it was deleted or replaced since the metadata message was sent.
+// Reading isn't limited by the read context, since the metadata message is already taken from the channel.
+func (i *Iterator) getContent(info *nats.ObjectInfo) (sdk.Data, error) {
+	if info.Deleted {
+		return nil, nil
+	}
+
+	if info.Size > i.maxContentSize {
+		return sdk.StructuredData{
+			"bucket": info.Bucket,
+			"name":   info.Name,
+			"nuid":   info.NUID,
+			"size":   info.Size,
+			"chunks": info.Chunks,
+			"digest": info.Digest,
+		}, nil
+	}
+
+	result, err := i.objectStore.Get(info.Name)
+	if err != nil {
+		if errors.Is(err, nats.ErrObjectNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("get object: %w", err)
+	}
+	defer result.Close()
+
+	resultInfo, err := result.Info()
+	if err != nil {
+		return nil, fmt.Errorf("get object info: %w", err)
+	}
+
+	if resultInfo.NUID != info.NUID {
+		return nil, nil
+	}
+
+	content, err := io.ReadAll(result)
+	if err != nil {
+		return nil, fmt.Errorf("read object: %w", err)
+	}
+
+	return sdk.RawData(content), nil
+}
+
+// trackName marks whether the object exists in the bucket.
+func (i *Iterator) trackName(info *nats.ObjectInfo) {
+	if info.Deleted {
+		delete(i.names, info.Name)
+
+		return
+	}
+
+	i.names[info.Name] = struct{}{}
+}
+
+// Ack does nothing, as metadata messages are delivered by an ordered consumer which doesn't need acknowledgements.
+// The position of the last processed object is used to resume watching the bucket.
+func (i *Iterator) Ack(sdk.Position) error {
+	return nil
+}
+
+// Stop unsubscribes from metadata messages and closes the connection.
+func (i *Iterator) Stop() error {
+	if i.subscription != nil {
+		if err := i.subscription.Unsubscribe(); err != nil {
+			return fmt.Errorf("unsubscribe: %w", err)
+		}
+	}
+
+	if i.conn != nil {
+		i.conn.Close()
+	}
+
+	return nil
+}
+
+// infoToRecord converts a nats.ObjectInfo along with the object contents to a sdk.Record.
+// An object becomes a create record if it isn't known to exist, and an update record otherwise.
+// A deleted object becomes a delete record.
+func (i *Iterator) infoToRecord(sequence uint64, info *nats.ObjectInfo, content sdk.Data) (sdk.Record, error) {
+	position, err := message.Position{Mode: message.PositionModeObject, Sequence: sequence}.MarshalSDKPosition()
+	if err != nil {
+		return sdk.Record{}, fmt.Errorf("marshal position: %w", err)
+	}
+
+	metadata := sdk.Metadata{
+		MetadataCollection: info.Bucket,
+		MetadataBucket:     info.Bucket,
+		MetadataName:       info.Name,
+		MetadataNUID:       info.NUID,
+		MetadataSize:       strconv.FormatUint(info.Size, 10),
+		MetadataChunks:     strconv.FormatUint(uint64(info.Chunks), 10),
+	}
+	metadata.SetCreatedAt(info.ModTime)
+
+	if info.Description != "" {
+		metadata[MetadataDescription] = info.Description
+	}
+
+	if info.Digest != "" {
+		metadata[MetadataDigest] = info.Digest
+	}
+
+	if _, ok := content.(sdk.StructuredData); ok {
+		metadata[MetadataReference] = "true"
+	}
+
+	message.SetHeaders(metadata, info.Headers, i.headerPrefix)
+
+	key := sdk.RawData(info.Name)
+
+	if info.Deleted {
+		return sdk.Util.Source.NewRecordDelete(position, metadata, key), nil
+	}
+
+	if _, ok := i.names[info.Name]; ok {
+		return sdk.Util.Source.NewRecordUpdate(position, metadata, key, nil, content), nil
+	}
+
+	return sdk.Util.Source.NewRecordCreate(position, metadata, key, content), nil
+}
@@ -0,0 +1,138 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"testing"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/nats-io/nats.go"
+)
+
+func TestIterator_infoToRecord(t *testing.T) {
+	t.Parallel()
+
+	info := nats.ObjectInfo{
+		ObjectMeta: nats.ObjectMeta{
+			Name:        "reports/2022-08.csv",
+			Description: "monthly report",
+			Headers:     nats.Header{"Content-Type": []string{"text/csv"}},
+		},
+		Bucket:  "files",
+		NUID:    "kLq8pQzQn4VU9HkV2Rms6S",
+		Size:    9,
+		ModTime: time.Unix(1661860800, 0),
+		Chunks:  1,
+		Digest:  "SHA-256=x1Jc8bMXG8wZO6pqsNqV9Hf9xFEqRUw6Vl8pL5iG_j0=",
+	}
+
+	tests := []struct {
+		name          string
+		names         map[string]struct{}
+		deleted       bool
+		content       sdk.Data
+		wantOperation sdk.Operation
+		wantReference bool
+	}{
+		{
+			name:          "success, new object",
+			names:         map[string]struct{}{},
+			content:       sdk.RawData("a,b\n1,2\n"),
+			wantOperation: sdk.OperationCreate,
+		},
+		{
+			name:          "success, replaced object",
+			names:         map[string]struct{}{info.Name: {}},
+			content:       sdk.RawData("a,b\n3,4\n"),
+			wantOperation: sdk.OperationUpdate,
+		},
+		{
+			name:  "success, large object",
+			names: map[string]struct{}{},
+			content: sdk.StructuredData{
+				"bucket": info.Bucket,
+				"name":   info.Name,
+			},
+			wantOperation: sdk.OperationCreate,
+			wantReference: true,
+		},
+		{
+			name:          "success, deleted object",
+			names:         map[string]struct{}{info.Name: {}},
+			deleted:       true,
+			wantOperation: sdk.OperationDelete,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			it := &Iterator{
+				headerPrefix: "nats.header.",
+				names:        tt.names,
+			}
+
+			info := info
+			info.Deleted = tt.deleted
+
+			record, err := it.infoToRecord(42, &info, tt.content)
+			if err != nil {
+				t.Fatalf("Iterator.infoToRecord() error = %v", err)
+			}
+
+			if record.Operation != tt.wantOperation {
+				t.Errorf("Iterator.infoToRecord() operation = %v, want %v", record.Operation, tt.wantOperation)
+			}
+
+			if string(record.Position) != `{"version":1,"mode":"object","sequence":42}` {
+				t.Errorf("Iterator.infoToRecord() position = %s, want %s", record.Position, `{"version":1,"mode":"object","sequence":42}`)
+			}
+
+			if string(record.Key.Bytes()) != info.Name {
+				t.Errorf("Iterator.infoToRecord() key = %s, want %s", record.Key.Bytes(), info.Name)
+			}
+
+			wantMetadata := sdk.Metadata{
+				MetadataCollection:         "files",
+				MetadataBucket:             "files",
+				MetadataName:               info.Name,
+				MetadataDescription:        info.Description,
+				MetadataNUID:               info.NUID,
+				MetadataSize:               "9",
+				MetadataChunks:             "1",
+				MetadataDigest:             info.Digest,
+				"nats.header.Content-Type": "text/csv",
+			}
+
+			for key, value := range wantMetadata {
+				if record.Metadata[key] != value {
+					t.Errorf("Iterator.infoToRecord() metadata[%q] = %q, want %q", key, record.Metadata[key], value)
+				}
+			}
+
+			if _, ok := record.Metadata[MetadataReference]; ok != tt.wantReference {
+				t.Errorf("Iterator.infoToRecord() reference = %t, want %t", ok, tt.wantReference)
+			}
+
+			if tt.content != nil && string(record.Payload.After.Bytes()) != string(tt.content.Bytes()) {
+				t.Errorf("Iterator.infoToRecord() payload = %s, want %s", record.Payload.After.Bytes(), tt.content.Bytes())
+			}
+		})
+	}
+}
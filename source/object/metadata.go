@@ -0,0 +1,38 @@
+// Copyright © 2022 Meroxa, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+const (
+	// MetadataCollection is a record metadata key for the name of a collection the record belongs to.
+	// The Iterator sets it to a name of a bucket.
+	MetadataCollection = "opencdc.collection"
+	// MetadataBucket is a record metadata key for a name of a bucket the object was read from.
+	MetadataBucket = "nats.object.bucket"
+	// MetadataName is a record metadata key for a name of an object.
+	MetadataName = "nats.object.name"
+	// MetadataDescription is a record metadata key for a description of an object.
+	MetadataDescription = "nats.object.description"
+	// MetadataNUID is a record metadata key for a unique identifier of an object version.
+	MetadataNUID = "nats.object.nuid"
+	// MetadataSize is a record metadata key for a size of an object in bytes.
+	MetadataSize = "nats.object.size"
+	// MetadataChunks is a record metadata key for a number of chunks an object is stored in.
+	MetadataChunks = "nats.object.chunks"
+	// MetadataDigest is a record metadata key for a digest of an object.
+	MetadataDigest = "nats.object.digest"
+	// MetadataReference is a record metadata key which is set to "true"
+	// if the record payload is a reference to an object instead of its contents.
+	MetadataReference = "nats.object.reference"
+)
@@ -16,21 +16,36 @@ package source
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/conduitio-labs/conduit-connector-nats-jetstream/common"
 	"github.com/conduitio-labs/conduit-connector-nats-jetstream/config"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/source/core"
 	"github.com/conduitio-labs/conduit-connector-nats-jetstream/source/jetstream"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/source/kv"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/source/object"
 	sdk "github.com/conduitio/conduit-connector-sdk"
 	"github.com/nats-io/nats.go"
 )
 
+// Iterator defines an iterator which converts messages of a communication model into records.
+type Iterator interface {
+	// Next returns the next record, it blocks until there is a record,
+	// an async error occurs or the context is done.
+	Next(ctx context.Context) (sdk.Record, error)
+	// Ack acknowledges a message at the given position.
+	Ack(position sdk.Position) error
+	// Stop stops the iterator and closes its connection.
+	Stop() error
+}
+
 // Source operates source logic.
 type Source struct {
 	sdk.UnimplementedSource
 
 	config   Config
-	iterator *jetstream.Iterator
+	iterator Iterator
 	errC     chan error
 }
 
@@ -41,16 +56,17 @@ func NewSource() sdk.Source {
 
 // Parameters is a map of named Parameters that describe how to configure the Source.
 func (s *Source) Parameters() map[string]sdk.Parameter {
-	return map[string]sdk.Parameter{
+	params := map[string]sdk.Parameter{
 		config.KeyURLs: {
 			Default:     "",
 			Required:    true,
 			Description: "The connection URLs pointed to NATS instances.",
 		},
 		config.KeySubject: {
-			Default:     "",
-			Required:    true,
-			Description: "A name of a subject from which the connector should read.",
+			Default:  "",
+			Required: false,
+			Description: "A name of a subject from which the connector should read, " +
+				"or a pattern of keys in the kv mode, not used in the object mode.",
 		},
 		config.KeyConnectionName: {
 			Default:     "conduit-connection-<uuid>",
@@ -97,6 +113,37 @@ func (s *Source) Parameters() map[string]sdk.Parameter {
 			Description: "Sets the time to backoff after attempting a reconnect " +
 				"to a server that we were already connected to previously.",
 		},
+		config.KeyMode: {
+			Default:  "jetstream",
+			Required: false,
+			Description: "Defines a communication model the source receives messages with. " +
+				"Allowed values are jetstream, kv, object and core. " +
+				"The core mode subscribes to a subject without JetStream and delivers messages at most once: " +
+				"messages published while the connector is stopped, dropped because of a slow consumer " +
+				"or left in the buffer on teardown are lost, and positions cannot be used to resume.",
+		},
+		config.KeyKVBucket: {
+			Default:     "",
+			Required:    false,
+			Description: "A name of a Key-Value bucket to watch, required for the kv mode.",
+		},
+		config.KeyObjectBucket: {
+			Default:     "",
+			Required:    false,
+			Description: "A name of an Object Store bucket to watch, required for the object mode.",
+		},
+		ConfigKeyQueueGroup: {
+			Default:  "",
+			Required: false,
+			Description: "A name of a queue group to subscribe in, used in the core mode. " +
+				"Each message is delivered to only one member of the group.",
+		},
+		ConfigKeyObjectMaxContentSize: {
+			Default:  "1048576",
+			Required: false,
+			Description: "The maximum size of an object in bytes whose contents are put into a record, " +
+				"records of larger objects contain references to them.",
+		},
 		ConfigKeyBufferSize: {
 			Default:     "1024",
 			Required:    false,
@@ -113,16 +160,121 @@ func (s *Source) Parameters() map[string]sdk.Parameter {
 			Description: "Specifies the JetStream consumer deliver subject.",
 		},
 		ConfigKeyDeliverPolicy: {
-			Default:     "all",
+			Default:  "all",
+			Required: false,
+			Description: "Defines where in the stream the connector should start receiving messages. " +
+				"Allowed values are all, new, last, last_per_subject, by_start_sequence and by_start_time.",
+		},
+		ConfigKeyStartSequence: {
+			Default:     "",
 			Required:    false,
-			Description: "Defines where in the stream the connector should start receiving messages.",
+			Description: "A stream sequence to start receiving messages from, required for the by_start_sequence policy.",
+		},
+		ConfigKeyStartTime: {
+			Default:  "",
+			Required: false,
+			Description: "An RFC3339 time to start receiving messages from, required for the by_start_time policy. " +
+				"The by_start_time policy is used if it's set and the deliverPolicy is empty.",
+		},
+		ConfigKeyEndTime: {
+			Default:  "",
+			Required: false,
+			Description: "An RFC3339 time to stop receiving messages at, the connector returns no messages " +
+				"created after it and takes the tailAction once all the earlier ones are acknowledged. " +
+				"Used in the jetstream mode.",
 		},
 		ConfigKeyAckPolicy: {
 			Default:     "explicit",
 			Required:    false,
 			Description: "Defines how messages should be acknowledged.",
 		},
+		ConfigKeyAckWait: {
+			Default:     "",
+			Required:    false,
+			Description: "How long the server waits for an acknowledgement before redelivering a message.",
+		},
+		ConfigKeyMaxDeliver: {
+			Default:     "",
+			Required:    false,
+			Description: "The maximum number of delivery attempts of a message, -1 means unlimited.",
+		},
+		ConfigKeyBackOff: {
+			Default:  "",
+			Required: false,
+			Description: "A comma-separated list of durations the server waits before each redelivery of a message. " +
+				"Cannot be set together with ackWait.",
+		},
+		ConfigKeyMaxAckPending: {
+			Default:     "",
+			Required:    false,
+			Description: "The maximum number of unacknowledged messages, -1 means unlimited.",
+		},
+		ConfigKeyConsumerLifecycle: {
+			Default:  "delete",
+			Required: false,
+			Description: "Defines what happens with the durable consumer on teardown. " +
+				"Allowed values are delete, retain and drain.",
+		},
+		ConfigKeyConsumerType: {
+			Default:     "push",
+			Required:    false,
+			Description: "Defines whether the connector uses a push or a pull consumer.",
+		},
+		ConfigKeyBatchSize: {
+			Default:     "256",
+			Required:    false,
+			Description: "The maximum number of messages a pull consumer fetches at once.",
+		},
+		ConfigKeyMaxWait: {
+			Default:     "5s",
+			Required:    false,
+			Description: "The maximum amount of time a pull consumer waits for a batch of messages.",
+		},
+		ConfigKeyHeaderPrefix: {
+			Default:     "nats.header.",
+			Required:    false,
+			Description: "A prefix of record metadata keys that hold message headers.",
+		},
+		ConfigKeyKeySource: {
+			Default:  "header:Nats-Msg-Id",
+			Required: false,
+			Description: "Defines where record keys are taken from in the format <type>:<value>, " +
+				"where the type is header, subject or payload.",
+		},
+		ConfigKeyStopAtTail: {
+			Default:  "false",
+			Required: false,
+			Description: "Makes the connector stop at the last message of the subject which was in the stream " +
+				"when the connector started, once all the messages up to it are acknowledged. Used in the jetstream mode.",
+		},
+		ConfigKeyPositionOverride: {
+			Default:  "false",
+			Required: false,
+			Description: "Makes the connector discard a stored position which belongs to a different stream " +
+				"or subject and start according to the deliverPolicy, instead of failing. Used in the jetstream mode.",
+		},
+		ConfigKeyPurgedPositionPolicy: {
+			Default:  "fail",
+			Required: false,
+			Description: "Defines what the connector does if messages following the position were removed " +
+				"from the stream, e.g. by the retention limits or a purge. Allowed values are fail, " +
+				"first_available (resume from the first message in the stream) and by_timestamp (resume from " +
+				"the first message not older than the one at the position). Used in the jetstream mode.",
+		},
+		ConfigKeyTailAction: {
+			Default:  "stop",
+			Required: false,
+			Description: "Defines what the connector does once it reaches the stream tail or the endTime. " +
+				"Allowed values are stop, which makes the connector return an error to end the pipeline, " +
+				"and idle, which makes it wait without returning records.",
+		},
+	}
+
+	for key, param := range common.StreamParameters() {
+		params[key] = param
 	}
+
+	return params
 }
 
 // Configure parses and initializes the config.
@@ -139,58 +291,172 @@ func (s *Source) Configure(_ context.Context, cfg map[string]string) error {
 }
 
 // Open opens a connection to NATS and initializes iterators.
-func (s *Source) Open(_ context.Context, position sdk.Position) error {
+func (s *Source) Open(ctx context.Context, position sdk.Position) error {
 	opts, err := common.GetConnectionOptions(s.config.Config)
 	if err != nil {
 		return fmt.Errorf("get connection options: %w", err)
 	}
 
+	// a connection closed by the client itself has no last error
+	opts = append(opts, common.GetConnectionEventOptions(ctx, func(err error) {
+		if err != nil {
+			s.reportError(fmt.Errorf("connection closed: %w", err))
+		}
+	})...)
+
 	conn, err := nats.Connect(s.config.ToURL(), opts...)
 	if err != nil {
 		return fmt.Errorf("connect to NATS: %w", err)
 	}
 
 	// register an error handler for async errors,
-	// the Source listens to them within the Read method and propagates the error if it occurs.
-	conn.SetErrorHandler(func(con *nats.Conn, sub *nats.Subscription, err error) {
-		s.errC <- err
-	})
+	// recoverable errors are only logged, and fatal ones are propagated within the Read method.
+	logger := sdk.Logger(ctx)
+	conn.SetErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
+		if !isFatalAsyncError(err) {
+			logger.Warn().Err(err).Msg("got a recoverable async error")
+
+			return
+		}
 
-	s.iterator, err = jetstream.NewIterator(jetstream.IteratorParams{
-		Conn:           conn,
-		BufferSize:     s.config.BufferSize,
-		Durable:        s.config.Durable,
-		DeliverSubject: s.config.DeliverSubject,
-		Subject:        s.config.Subject,
-		SDKPosition:    position,
-		DeliverPolicy:  s.config.DeliverPolicy,
-		AckPolicy:      s.config.AckPolicy,
+		s.reportError(err)
 	})
+
+	// the stream is provisioned only in the jetstream mode, which is validated by the config
+	if err := common.ProvisionStream(ctx, conn, s.config.Stream); err != nil {
+		conn.Close()
+
+		return fmt.Errorf("provision stream: %w", err)
+	}
+
+	s.iterator, err = s.newIterator(ctx, conn, position)
 	if err != nil {
-		return fmt.Errorf("init jetstream iterator: %w", err)
+		conn.Close()
+
+		return err
 	}
 
 	return nil
 }
 
-// Read fetches a record from an iterator.
-// If there's no record will return sdk.ErrBackoffRetry.
-func (s *Source) Read(ctx context.Context) (sdk.Record, error) {
-	select {
-	case err := <-s.errC:
-		return sdk.Record{}, fmt.Errorf("got an async error: %w", err)
+// newIterator creates an iterator for the configured mode.
+func (s *Source) newIterator(ctx context.Context, conn *nats.Conn, position sdk.Position) (Iterator, error) {
+	switch s.config.Mode {
+	case config.ModeJetStream:
+		iterator, err := jetstream.NewIterator(ctx, jetstream.IteratorParams{
+			Conn:              conn,
+			BufferSize:        s.config.BufferSize,
+			Durable:           s.config.Durable,
+			DeliverSubject:    s.config.DeliverSubject,
+			Subject:           s.config.Subject,
+			SDKPosition:       position,
+			DeliverPolicy:     s.config.DeliverPolicy,
+			StartSequence:     s.config.StartSequence,
+			StartTime:         s.config.StartTime,
+			AckPolicy:         s.config.AckPolicy,
+			AckWait:           s.config.AckWait,
+			MaxDeliver:        s.config.MaxDeliver,
+			BackOff:           s.config.BackOff,
+			MaxAckPending:     s.config.MaxAckPending,
+			ConsumerType:      s.config.ConsumerType,
+			ConsumerLifecycle: s.config.ConsumerLifecycle,
+			BatchSize:         s.config.BatchSize,
+			MaxWait:           s.config.MaxWait,
+			HeaderPrefix:      s.config.HeaderPrefix,
+			KeySource:         s.config.KeySource,
+			ErrC:              s.errC,
+			StopAtTail:        s.config.StopAtTail,
+			EndTime:           s.config.EndTime,
+			TailAction:        s.config.TailAction,
+			PositionOverride:  s.config.PositionOverride,
+			PurgedPolicy:      s.config.PurgedPositionPolicy,
+		})
+		if err != nil {
+			if errors.Is(err, jetstream.ErrPositionMismatch) {
+				return nil, fmt.Errorf("init jetstream iterator: %w; set %q to true to discard the position",
+					err, ConfigKeyPositionOverride)
+			}
 
-	default:
-		if !s.iterator.HasNext() {
-			return sdk.Record{}, sdk.ErrBackoffRetry
+			if errors.Is(err, jetstream.ErrSequencePurged) {
+				return nil, fmt.Errorf("init jetstream iterator: %w; set %q to resume despite the gap",
+					err, ConfigKeyPurgedPositionPolicy)
+			}
+
+			return nil, fmt.Errorf("init jetstream iterator: %w", err)
 		}
 
-		record, err := s.iterator.Next(ctx)
+		return iterator, nil
+
+	case config.ModeKV:
+		iterator, err := kv.NewIterator(kv.IteratorParams{
+			Conn:        conn,
+			Bucket:      s.config.KVBucket,
+			Keys:        s.config.Subject,
+			SDKPosition: position,
+			ErrC:        s.errC,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("init kv iterator: %w", err)
+		}
+
+		return iterator, nil
+
+	case config.ModeObject:
+		iterator, err := object.NewIterator(object.IteratorParams{
+			Conn:           conn,
+			Bucket:         s.config.ObjectBucket,
+			BufferSize:     s.config.BufferSize,
+			SDKPosition:    position,
+			MaxContentSize: s.config.ObjectMaxContentSize,
+			HeaderPrefix:   s.config.HeaderPrefix,
+			ErrC:           s.errC,
+		})
 		if err != nil {
-			return sdk.Record{}, fmt.Errorf("read next record: %w", err)
+			return nil, fmt.Errorf("init object iterator: %w", err)
 		}
 
-		return record, nil
+		return iterator, nil
+
+	case config.ModeCore:
+		iterator, err := core.NewIterator(core.IteratorParams{
+			Conn:         conn,
+			BufferSize:   s.config.BufferSize,
+			Subject:      s.config.Subject,
+			QueueGroup:   s.config.QueueGroup,
+			SDKPosition:  position,
+			HeaderPrefix: s.config.HeaderPrefix,
+			KeySource:    s.config.KeySource,
+			ErrC:         s.errC,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("init core iterator: %w", err)
+		}
+
+		return iterator, nil
+
+	default:
+		return nil, fmt.Errorf("unknown mode %d", s.config.Mode)
+	}
+}
+
+// Read fetches a record from an iterator.
+// It blocks until there is a record, an async error occurs or the context is done,
+// so records are returned as soon as they arrive, without the SDK backing off between empty reads.
+func (s *Source) Read(ctx context.Context) (sdk.Record, error) {
+	record, err := s.iterator.Next(ctx)
+	if err != nil {
+		return sdk.Record{}, fmt.Errorf("read next record: %w", err)
+	}
+
+	return record, nil
+}
+
+// reportError passes a fatal error to Read without blocking the caller,
+// the errors that occur while another one is pending are dropped, since Read returns only the first one.
+func (s *Source) reportError(err error) {
+	select {
+	case s.errC <- err:
+	default:
 	}
 }
 
@@ -19,12 +19,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/conduitio-labs/conduit-connector-nats-jetstream/config"
+	"github.com/conduitio-labs/conduit-connector-nats-jetstream/source/jetstream"
 	"github.com/conduitio-labs/conduit-connector-nats-jetstream/test"
 	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/nats-io/nats.go"
 )
 
 func TestSource_Open(t *testing.T) {
@@ -101,7 +104,7 @@ func TestSource_Read_JetStream_oneMessage(t *testing.T) {
 	}
 }
 
-func TestSource_Read_JetStream_backoffRetry(t *testing.T) {
+func TestSource_Read_JetStream_noBackoffRetry(t *testing.T) {
 	t.Parallel()
 
 	stream, subject := "mystreamtwo", "foo_two"
@@ -119,26 +122,1397 @@ func TestSource_Read_JetStream_backoffRetry(t *testing.T) {
 		}
 	})
 
+	// there are no messages, so Read keeps waiting until the context is done
+	// instead of returning the sdk.ErrBackoffRetry
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	started := time.Now()
+
+	_, err = source.Read(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Source.Read error = %v, want %v", err, context.DeadlineExceeded)
+
+		return
+	}
+
+	if elapsed := time.Since(started); elapsed < 2*time.Second {
+		t.Fatalf("Source.Read returned after %s, want it to block until the context is done", elapsed)
+	}
+}
+
+func TestSource_Read_JetStream_blocking(t *testing.T) {
+	t.Parallel()
+
+	stream, subject := "mystreamblocking", "foo_blocking"
+
+	source, err := createTestJetStream(stream, subject)
+	if err != nil {
+		t.Fatalf("create test jetstream: %v", err)
+
+		return
+	}
+
+	t.Cleanup(func() {
+		if err := source.Teardown(context.Background()); err != nil {
+			t.Fatalf("teardown source: %v", err)
+		}
+	})
+
+	testConn, err := test.GetTestConnection()
+	if err != nil {
+		t.Fatalf("get test connection: %v", err)
+
+		return
+	}
+
+	// the message is published while Read is already waiting for it
+	time.AfterFunc(100*time.Millisecond, func() {
+		if err := testConn.Publish(subject, []byte(`{"level": "info"}`)); err != nil {
+			t.Errorf("publish message: %v", err)
+		}
+	})
+
+	record, err := source.Read(context.Background())
+	if err != nil {
+		t.Fatalf("read message: %v", err)
+
+		return
+	}
+
+	if !bytes.Equal(record.Payload.After.Bytes(), []byte(`{"level": "info"}`)) {
+		t.Fatalf("Source.Read = %s, want %s", record.Payload.After.Bytes(), `{"level": "info"}`)
+	}
+}
+
+func TestSource_Read_JetStream_pullConsumer(t *testing.T) {
+	t.Parallel()
+
+	stream, subject := "mystreampull", "foo_pull"
+
+	source, err := createTestJetStreamWithConfig(stream, map[string]string{
+		config.KeyURLs:        test.TestURL,
+		config.KeySubject:     subject,
+		ConfigKeyConsumerType: "pull",
+		ConfigKeyMaxWait:      "100ms",
+	})
+	if err != nil {
+		t.Fatalf("create test jetstream: %v", err)
+
+		return
+	}
+
+	t.Cleanup(func() {
+		if err := source.Teardown(context.Background()); err != nil {
+			t.Fatalf("teardown source: %v", err)
+		}
+	})
+
+	testConn, err := test.GetTestConnection()
+	if err != nil {
+		t.Fatalf("get test connection: %v", err)
+
+		return
+	}
+
+	for _, data := range []string{`{"level": "info"}`, `{"level": "warn"}`} {
+		if _, err = testConn.Request(subject, []byte(data), time.Second); err != nil {
+			t.Fatalf("publish message: %v", err)
+
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	for _, want := range []string{`{"level": "info"}`, `{"level": "warn"}`} {
+		var record sdk.Record
+		for {
+			record, err = source.Read(ctx)
+			if err != nil {
+				if errors.Is(err, sdk.ErrBackoffRetry) {
+					continue
+				}
+				t.Fatalf("read message: %v", err)
+
+				return
+			}
+
+			break
+		}
+
+		if !bytes.Equal(record.Payload.After.Bytes(), []byte(want)) {
+			t.Fatalf("Source.Read = %s, want %s", record.Payload.After.Bytes(), want)
+
+			return
+		}
+
+		if err := source.Ack(ctx, record.Position); err != nil {
+			t.Fatalf("ack message: %v", err)
+
+			return
+		}
+	}
+}
+
+func TestSource_Read_JetStream_pullConsumerAsyncError(t *testing.T) {
+	t.Parallel()
+
+	stream, subject := "mystreampullasyncerror", "foo_pull_async_error"
+
+	testConn, err := test.GetTestConnection()
+	if err != nil {
+		t.Fatalf("get test connection: %v", err)
+
+		return
+	}
+
+	err = test.CreateTestStream(testConn, stream, []string{subject})
+	if err != nil {
+		t.Fatalf("add stream: %v", err)
+
+		return
+	}
+
+	// the source isn't wrapped with middleware, so the test can report an async error
+	source := &Source{}
+
+	err = source.Configure(context.Background(), map[string]string{
+		config.KeyURLs:        test.TestURL,
+		config.KeySubject:     subject,
+		ConfigKeyConsumerType: "pull",
+		ConfigKeyMaxWait:      "10s",
+	})
+	if err != nil {
+		t.Fatalf("configure source: %v", err)
+
+		return
+	}
+
+	if err = source.Open(context.Background(), nil); err != nil {
+		t.Fatalf("open source: %v", err)
+
+		return
+	}
+
+	t.Cleanup(func() {
+		if err := source.Teardown(context.Background()); err != nil {
+			t.Fatalf("teardown source: %v", err)
+		}
+	})
+
+	// the stream is empty, so Read waits within a fetch request when the error is reported
+	time.AfterFunc(200*time.Millisecond, func() {
+		source.reportError(nats.ErrConsumerDeleted)
+	})
+
+	started := time.Now()
+
 	_, err = source.Read(context.Background())
-	if err == nil {
-		t.Fatal("Source.Read expected backoff retry error, got nil")
+	if !errors.Is(err, nats.ErrConsumerDeleted) {
+		t.Fatalf("Source.Read error = %v, want %v", err, nats.ErrConsumerDeleted)
+
+		return
+	}
+
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Fatalf("Source.Read returned the async error after %s, want it within the max wait", elapsed)
+
+		return
+	}
+}
+
+func TestSource_Read_JetStream_byStartSequence(t *testing.T) {
+	t.Parallel()
+
+	stream, subject := "mystreamstartseq", "foo_start_seq"
+
+	testConn, err := test.GetTestConnection()
+	if err != nil {
+		t.Fatalf("get test connection: %v", err)
+
+		return
+	}
+
+	err = test.CreateTestStream(testConn, stream, []string{subject})
+	if err != nil {
+		t.Fatalf("add stream: %v", err)
+
+		return
+	}
+
+	for _, data := range []string{`{"id": 1}`, `{"id": 2}`, `{"id": 3}`} {
+		if _, err = testConn.Request(subject, []byte(data), time.Second); err != nil {
+			t.Fatalf("publish message: %v", err)
+
+			return
+		}
+	}
+
+	source := NewSource()
+	err = source.Configure(context.Background(), map[string]string{
+		config.KeyURLs:         test.TestURL,
+		config.KeySubject:      subject,
+		ConfigKeyDeliverPolicy: "by_start_sequence",
+		ConfigKeyStartSequence: "2",
+	})
+	if err != nil {
+		t.Fatalf("configure source: %v", err)
+
+		return
+	}
+
+	if err = source.Open(context.Background(), nil); err != nil {
+		t.Fatalf("open source: %v", err)
+
+		return
+	}
+
+	t.Cleanup(func() {
+		if err := source.Teardown(context.Background()); err != nil {
+			t.Fatalf("teardown source: %v", err)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	var record sdk.Record
+	for {
+		record, err = source.Read(ctx)
+		if err != nil {
+			if errors.Is(err, sdk.ErrBackoffRetry) {
+				continue
+			}
+			t.Fatalf("read message: %v", err)
+
+			return
+		}
+
+		break
+	}
+
+	if !bytes.Equal(record.Payload.After.Bytes(), []byte(`{"id": 2}`)) {
+		t.Fatalf("Source.Read = %s, want %s", record.Payload.After.Bytes(), `{"id": 2}`)
+
+		return
+	}
+}
+
+func TestSource_Open_JetStream_positionMismatch(t *testing.T) {
+	t.Parallel()
+
+	stream, subject := "mystreampositionmismatch", "foo_position_mismatch"
+
+	testConn, err := test.GetTestConnection()
+	if err != nil {
+		t.Fatalf("get test connection: %v", err)
+
+		return
+	}
+
+	err = test.CreateTestStream(testConn, stream, []string{subject})
+	if err != nil {
+		t.Fatalf("add stream: %v", err)
+
+		return
+	}
+
+	for _, data := range []string{`{"id": 1}`, `{"id": 2}`} {
+		if _, err = testConn.Request(subject, []byte(data), time.Second); err != nil {
+			t.Fatalf("publish message: %v", err)
+
+			return
+		}
+	}
+
+	// the position was stored by a source which read another stream
+	position := sdk.Position(`{"version":1,"stream":"otherstream","subject":"other_subject","sequence":1}`)
+
+	cfg := map[string]string{
+		config.KeyURLs:    test.TestURL,
+		config.KeySubject: subject,
+	}
+
+	source := NewSource()
+	if err = source.Configure(context.Background(), cfg); err != nil {
+		t.Fatalf("configure source: %v", err)
+
+		return
+	}
+
+	err = source.Open(context.Background(), position)
+	if !errors.Is(err, jetstream.ErrPositionMismatch) {
+		t.Fatalf("Source.Open error = %v, want %v", err, jetstream.ErrPositionMismatch)
+
+		return
+	}
+
+	// the position is discarded, so the source starts from the first message according to the deliver policy
+	cfg[ConfigKeyPositionOverride] = "true"
+
+	source = NewSource()
+	if err = source.Configure(context.Background(), cfg); err != nil {
+		t.Fatalf("configure source: %v", err)
+
+		return
+	}
+
+	if err = source.Open(context.Background(), position); err != nil {
+		t.Fatalf("open source: %v", err)
+
+		return
+	}
+
+	t.Cleanup(func() {
+		if err := source.Teardown(context.Background()); err != nil {
+			t.Fatalf("teardown source: %v", err)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	var record sdk.Record
+	for {
+		record, err = source.Read(ctx)
+		if err != nil {
+			if errors.Is(err, sdk.ErrBackoffRetry) {
+				continue
+			}
+			t.Fatalf("read message: %v", err)
+
+			return
+		}
+
+		break
+	}
+
+	if !bytes.Equal(record.Payload.After.Bytes(), []byte(`{"id": 1}`)) {
+		t.Fatalf("Source.Read = %s, want %s", record.Payload.After.Bytes(), `{"id": 1}`)
+
+		return
+	}
+
+	if !bytes.Contains(record.Position, []byte(`"stream":"mystreampositionmismatch"`)) {
+		t.Fatalf("Source.Read position = %s, want the stream %s", record.Position, stream)
+
+		return
+	}
+}
+
+func TestSource_Open_JetStream_purgedPosition(t *testing.T) {
+	t.Parallel()
+
+	stream, subject := "mystreampurgedposition", "foo_purged_position"
+
+	testConn, err := test.GetTestConnection()
+	if err != nil {
+		t.Fatalf("get test connection: %v", err)
+
+		return
+	}
+
+	err = test.CreateTestStream(testConn, stream, []string{subject})
+	if err != nil {
+		t.Fatalf("add stream: %v", err)
+
+		return
+	}
+
+	for _, data := range []string{`{"id": 1}`, `{"id": 2}`, `{"id": 3}`} {
+		if _, err = testConn.Request(subject, []byte(data), time.Second); err != nil {
+			t.Fatalf("publish message: %v", err)
+
+			return
+		}
+	}
+
+	js, err := testConn.JetStream()
+	if err != nil {
+		t.Fatalf("get jetstream context: %v", err)
+
+		return
+	}
+
+	msg, err := js.GetMsg(stream, 1)
+	if err != nil {
+		t.Fatalf("get message: %v", err)
+
+		return
+	}
+
+	// the position points to the first message, and the ones following it are purged
+	position := sdk.Position(fmt.Sprintf(`{"version":1,"stream":%q,"subject":%q,"sequence":1,"timestamp":%q}`,
+		stream, subject, msg.Time.Format(time.RFC3339Nano)))
+
+	if err = js.PurgeStream(stream); err != nil {
+		t.Fatalf("purge stream: %v", err)
+
+		return
+	}
+
+	if _, err = testConn.Request(subject, []byte(`{"id": 4}`), time.Second); err != nil {
+		t.Fatalf("publish message: %v", err)
+
+		return
+	}
+
+	tests := []struct {
+		policy  string
+		want    []byte
+		wantErr error
+	}{
+		{policy: "", wantErr: jetstream.ErrSequencePurged},
+		{policy: "first_available", want: []byte(`{"id": 4}`)},
+		{policy: "by_timestamp", want: []byte(`{"id": 4}`)},
+	}
+
+	for _, tt := range tests {
+		source := NewSource()
+
+		err = source.Configure(context.Background(), map[string]string{
+			config.KeyURLs:                test.TestURL,
+			config.KeySubject:             subject,
+			ConfigKeyPurgedPositionPolicy: tt.policy,
+		})
+		if err != nil {
+			t.Fatalf("configure source: %v", err)
+
+			return
+		}
+
+		err = source.Open(context.Background(), position)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Source.Open error = %v, want %v", err, tt.wantErr)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("open source: %v", err)
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+
+		var record sdk.Record
+		for {
+			record, err = source.Read(ctx)
+			if !errors.Is(err, sdk.ErrBackoffRetry) {
+				break
+			}
+		}
+
+		cancel()
+
+		if err != nil {
+			t.Fatalf("read message: %v", err)
+
+			return
+		}
+
+		if !bytes.Equal(record.Payload.After.Bytes(), tt.want) {
+			t.Fatalf("Source.Read with the %q policy = %s, want %s", tt.policy, record.Payload.After.Bytes(), tt.want)
+
+			return
+		}
+
+		if err = source.Teardown(context.Background()); err != nil {
+			t.Fatalf("teardown source: %v", err)
+
+			return
+		}
+	}
+}
+
+func TestSource_Read_JetStream_stopAtTail(t *testing.T) {
+	t.Parallel()
+
+	stream, subject := "mystreamstopattail", "foo_stop_at_tail"
+
+	testConn, err := test.GetTestConnection()
+	if err != nil {
+		t.Fatalf("get test connection: %v", err)
+
+		return
+	}
+
+	err = test.CreateTestStream(testConn, stream, []string{subject})
+	if err != nil {
+		t.Fatalf("add stream: %v", err)
+
+		return
+	}
+
+	for _, data := range []string{`{"id": 1}`, `{"id": 2}`} {
+		if _, err = testConn.Request(subject, []byte(data), time.Second); err != nil {
+			t.Fatalf("publish message: %v", err)
+
+			return
+		}
+	}
+
+	source := NewSource()
+	err = source.Configure(context.Background(), map[string]string{
+		config.KeyURLs:      test.TestURL,
+		config.KeySubject:   subject,
+		ConfigKeyStopAtTail: "true",
+	})
+	if err != nil {
+		t.Fatalf("configure source: %v", err)
+
+		return
+	}
+
+	if err = source.Open(context.Background(), nil); err != nil {
+		t.Fatalf("open source: %v", err)
+
+		return
+	}
+
+	t.Cleanup(func() {
+		if err := source.Teardown(context.Background()); err != nil {
+			t.Fatalf("teardown source: %v", err)
+		}
+	})
+
+	// the message published after the source opened is after the tail
+	if _, err = testConn.Request(subject, []byte(`{"id": 3}`), time.Second); err != nil {
+		t.Fatalf("publish message: %v", err)
+
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var read []string
+	for {
+		record, err := source.Read(ctx)
+		if err != nil {
+			if errors.Is(err, sdk.ErrBackoffRetry) {
+				continue
+			}
+
+			if errors.Is(err, jetstream.ErrTailReached) {
+				break
+			}
+
+			t.Fatalf("read message: %v", err)
+
+			return
+		}
+
+		read = append(read, string(record.Payload.After.Bytes()))
+
+		if err := source.Ack(ctx, record.Position); err != nil {
+			t.Fatalf("ack message: %v", err)
+
+			return
+		}
+	}
+
+	if want := []string{`{"id": 1}`, `{"id": 2}`}; !reflect.DeepEqual(read, want) {
+		t.Fatalf("Source.Read = %v, want %v", read, want)
+
+		return
+	}
+}
+
+func TestSource_Read_JetStream_timeRange(t *testing.T) {
+	t.Parallel()
+
+	stream, subject := "mystreamtimerange", "foo_time_range"
+
+	testConn, err := test.GetTestConnection()
+	if err != nil {
+		t.Fatalf("get test connection: %v", err)
+
+		return
+	}
+
+	err = test.CreateTestStream(testConn, stream, []string{subject})
+	if err != nil {
+		t.Fatalf("add stream: %v", err)
+
+		return
+	}
+
+	publish := func(data string) {
+		if _, err = testConn.Request(subject, []byte(data), time.Second); err != nil {
+			t.Fatalf("publish message: %v", err)
+		}
+	}
+
+	publish(`{"id": 1}`)
+	time.Sleep(10 * time.Millisecond)
+
+	startTime := time.Now()
+
+	publish(`{"id": 2}`)
+	publish(`{"id": 3}`)
+	time.Sleep(10 * time.Millisecond)
+
+	endTime := time.Now()
+
+	publish(`{"id": 4}`)
+
+	source := NewSource()
+	err = source.Configure(context.Background(), map[string]string{
+		config.KeyURLs:     test.TestURL,
+		config.KeySubject:  subject,
+		ConfigKeyStartTime: startTime.Format(time.RFC3339Nano),
+		ConfigKeyEndTime:   endTime.Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		t.Fatalf("configure source: %v", err)
+
+		return
+	}
+
+	if err = source.Open(context.Background(), nil); err != nil {
+		t.Fatalf("open source: %v", err)
+
+		return
+	}
+
+	t.Cleanup(func() {
+		if err := source.Teardown(context.Background()); err != nil {
+			t.Fatalf("teardown source: %v", err)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var read []string
+	for {
+		record, err := source.Read(ctx)
+		if err != nil {
+			if errors.Is(err, sdk.ErrBackoffRetry) {
+				continue
+			}
+
+			if errors.Is(err, jetstream.ErrEndTimeReached) {
+				break
+			}
+
+			t.Fatalf("read message: %v", err)
+
+			return
+		}
+
+		if !bytes.Contains(record.Position, []byte(`"end_time"`)) {
+			t.Fatalf("Source.Read position = %s, want the end time", record.Position)
+
+			return
+		}
+
+		read = append(read, string(record.Payload.After.Bytes()))
+
+		if err := source.Ack(ctx, record.Position); err != nil {
+			t.Fatalf("ack message: %v", err)
+
+			return
+		}
+	}
+
+	if want := []string{`{"id": 2}`, `{"id": 3}`}; !reflect.DeepEqual(read, want) {
+		t.Fatalf("Source.Read = %v, want %v", read, want)
+
+		return
+	}
+}
+
+func TestSource_Ack_JetStream_outOfOrder(t *testing.T) {
+	t.Parallel()
+
+	stream, subject := "mystreamackorder", "foo_ack_order"
+
+	source, err := createTestJetStream(stream, subject)
+	if err != nil {
+		t.Fatalf("create test jetstream: %v", err)
+
+		return
+	}
+
+	t.Cleanup(func() {
+		if err := source.Teardown(context.Background()); err != nil {
+			t.Fatalf("teardown source: %v", err)
+		}
+	})
+
+	testConn, err := test.GetTestConnection()
+	if err != nil {
+		t.Fatalf("get test connection: %v", err)
+
+		return
+	}
+
+	for _, data := range []string{`{"id": 1}`, `{"id": 2}`, `{"id": 3}`} {
+		if _, err = testConn.Request(subject, []byte(data), time.Second); err != nil {
+			t.Fatalf("publish message: %v", err)
+
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	positions := make([]sdk.Position, 0, 3)
+	for len(positions) < 3 {
+		record, err := source.Read(ctx)
+		if err != nil {
+			if errors.Is(err, sdk.ErrBackoffRetry) {
+				continue
+			}
+			t.Fatalf("read message: %v", err)
+
+			return
+		}
+
+		positions = append(positions, record.Position)
+	}
+
+	// ack in reverse order and ack the last one twice
+	for _, position := range []sdk.Position{positions[2], positions[0], positions[1], positions[1]} {
+		if err := source.Ack(ctx, position); err != nil {
+			t.Fatalf("ack message at %s: %v", position, err)
+
+			return
+		}
+	}
+}
+
+func TestSource_Open_JetStream_redeliveryOptions(t *testing.T) {
+	t.Parallel()
+
+	stream, subject, durable := "mystreamredelivery", "foo_redelivery", "redelivery_durable"
+
+	source, err := createTestJetStreamWithConfig(stream, map[string]string{
+		config.KeyURLs:         test.TestURL,
+		config.KeySubject:      subject,
+		ConfigKeyDurable:       durable,
+		ConfigKeyMaxDeliver:    "3",
+		ConfigKeyBackOff:       "1s,5s",
+		ConfigKeyMaxAckPending: "10",
+	})
+	if err != nil {
+		t.Fatalf("create test jetstream: %v", err)
+
+		return
+	}
+
+	t.Cleanup(func() {
+		if err := source.Teardown(context.Background()); err != nil {
+			t.Fatalf("teardown source: %v", err)
+		}
+	})
+
+	testConn, err := test.GetTestConnection()
+	if err != nil {
+		t.Fatalf("get test connection: %v", err)
+
+		return
+	}
+
+	js, err := testConn.JetStream()
+	if err != nil {
+		t.Fatalf("get jetstream context: %v", err)
+
+		return
+	}
+
+	consumerInfo, err := js.ConsumerInfo(stream, durable)
+	if err != nil {
+		t.Fatalf("get consumer info: %v", err)
+
+		return
+	}
+
+	if consumerInfo.Config.MaxDeliver != 3 {
+		t.Errorf("MaxDeliver = %d, want %d", consumerInfo.Config.MaxDeliver, 3)
+	}
+
+	if consumerInfo.Config.MaxAckPending != 10 {
+		t.Errorf("MaxAckPending = %d, want %d", consumerInfo.Config.MaxAckPending, 10)
+	}
+
+	// the server uses the first backoff duration as the ack wait
+	if consumerInfo.Config.AckWait != time.Second {
+		t.Errorf("AckWait = %s, want %s", consumerInfo.Config.AckWait, time.Second)
+	}
+}
+
+func TestSource_Teardown_JetStream_consumerLifecycle(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name              string
+		stream            string
+		consumerLifecycle string
+		wantConsumer      bool
+	}{
+		{
+			name:              "delete",
+			stream:            "mystreamlifecycledelete",
+			consumerLifecycle: "delete",
+			wantConsumer:      false,
+		},
+		{
+			name:              "retain",
+			stream:            "mystreamlifecycleretain",
+			consumerLifecycle: "retain",
+			wantConsumer:      true,
+		},
+		{
+			name:              "drain",
+			stream:            "mystreamlifecycledrain",
+			consumerLifecycle: "drain",
+			wantConsumer:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			subject, durable := tt.stream+"_subject", tt.stream+"_durable"
+
+			source, err := createTestJetStreamWithConfig(tt.stream, map[string]string{
+				config.KeyURLs:             test.TestURL,
+				config.KeySubject:          subject,
+				ConfigKeyDurable:           durable,
+				ConfigKeyConsumerLifecycle: tt.consumerLifecycle,
+			})
+			if err != nil {
+				t.Fatalf("create test jetstream: %v", err)
+
+				return
+			}
+
+			if err := source.Teardown(context.Background()); err != nil {
+				t.Fatalf("teardown source: %v", err)
+
+				return
+			}
+
+			testConn, err := test.GetTestConnection()
+			if err != nil {
+				t.Fatalf("get test connection: %v", err)
+
+				return
+			}
+
+			js, err := testConn.JetStream()
+			if err != nil {
+				t.Fatalf("get jetstream context: %v", err)
+
+				return
+			}
+
+			_, err = js.ConsumerInfo(tt.stream, durable)
+			if tt.wantConsumer && err != nil {
+				t.Fatalf("get consumer info: %v", err)
+			}
+
+			if !tt.wantConsumer && !errors.Is(err, nats.ErrConsumerNotFound) {
+				t.Fatalf("get consumer info error = %v, want %v", err, nats.ErrConsumerNotFound)
+			}
+		})
+	}
+}
+
+func TestSource_Open_JetStream_retainedConsumer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name              string
+		stream            string
+		consumerLifecycle string
+		ackWait           string
+	}{
+		{
+			// the unacknowledged message is redelivered once the ack wait expires
+			name:              "retain",
+			stream:            "mystreamlifecycleretain",
+			consumerLifecycle: "retain",
+			ackWait:           "1s",
+		},
+		{
+			// the unacknowledged message is redelivered right away
+			name:              "drain",
+			stream:            "mystreamlifecycledrain",
+			consumerLifecycle: "drain",
+			ackWait:           "",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			subject, durable := tt.stream+"_subject", tt.stream+"_durable"
+			cfg := map[string]string{
+				config.KeyURLs:             test.TestURL,
+				config.KeySubject:          subject,
+				ConfigKeyDurable:           durable,
+				ConfigKeyConsumerLifecycle: tt.consumerLifecycle,
+				ConfigKeyAckWait:           tt.ackWait,
+			}
+
+			source, err := createTestJetStreamWithConfig(tt.stream, cfg)
+			if err != nil {
+				t.Fatalf("create test jetstream: %v", err)
+
+				return
+			}
+
+			testConn, err := test.GetTestConnection()
+			if err != nil {
+				t.Fatalf("get test connection: %v", err)
+
+				return
+			}
+
+			for _, data := range []string{`{"id": 1}`, `{"id": 2}`} {
+				if _, err = testConn.Request(subject, []byte(data), time.Second); err != nil {
+					t.Fatalf("publish message: %v", err)
+
+					return
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+			defer cancel()
+
+			record, err := readTestRecord(ctx, source)
+			if err != nil {
+				t.Fatalf("read message: %v", err)
+
+				return
+			}
+
+			if err := source.Ack(ctx, record.Position); err != nil {
+				t.Fatalf("ack message: %v", err)
+
+				return
+			}
+
+			if err := source.Teardown(ctx); err != nil {
+				t.Fatalf("teardown source: %v", err)
+
+				return
+			}
+
+			// open a new source which must re-bind to the kept consumer
+			source = NewSource()
+			if err := source.Configure(ctx, cfg); err != nil {
+				t.Fatalf("configure source: %v", err)
+
+				return
+			}
+
+			if err := source.Open(ctx, record.Position); err != nil {
+				t.Fatalf("open source: %v", err)
+
+				return
+			}
+
+			t.Cleanup(func() {
+				if err := source.Teardown(context.Background()); err != nil {
+					t.Fatalf("teardown source: %v", err)
+				}
+			})
+
+			record, err = readTestRecord(ctx, source)
+			if err != nil {
+				t.Fatalf("read message: %v", err)
+
+				return
+			}
+
+			if !bytes.Equal(record.Payload.After.Bytes(), []byte(`{"id": 2}`)) {
+				t.Fatalf("Source.Read = %s, want %s", record.Payload.After.Bytes(), `{"id": 2}`)
+			}
+		})
+	}
+}
+
+// readTestRecord reads records until it gets one or the context is done.
+func readTestRecord(ctx context.Context, source sdk.Source) (sdk.Record, error) {
+	for {
+		record, err := source.Read(ctx)
+		if err != nil {
+			if errors.Is(err, sdk.ErrBackoffRetry) && ctx.Err() == nil {
+				continue
+			}
+
+			return sdk.Record{}, err
+		}
+
+		return record, nil
+	}
+}
+
+func TestSource_Read_KeyValue(t *testing.T) {
+	t.Parallel()
+
+	testConn, err := test.GetTestConnection()
+	if err != nil {
+		t.Fatalf("get test connection: %v", err)
 
 		return
 	}
 
-	if err != nil && !errors.Is(err, sdk.ErrBackoffRetry) {
-		t.Fatalf("read message: %v", err)
+	keyValue, err := test.CreateTestKeyValue(testConn, "settings_read")
+	if err != nil {
+		t.Fatalf("create test key value: %v", err)
+
+		return
+	}
+
+	cfg := map[string]string{
+		config.KeyURLs:     test.TestURL,
+		config.KeyMode:     "kv",
+		config.KeyKVBucket: "settings_read",
+	}
+
+	source := NewSource()
+	if err := source.Configure(context.Background(), cfg); err != nil {
+		t.Fatalf("configure source: %v", err)
+
+		return
+	}
+
+	if err := source.Open(context.Background(), nil); err != nil {
+		t.Fatalf("open source: %v", err)
+
+		return
+	}
+
+	if _, err := keyValue.Put("a", []byte("1")); err != nil {
+		t.Fatalf("put entry: %v", err)
+
+		return
+	}
+
+	if _, err := keyValue.Put("a", []byte("2")); err != nil {
+		t.Fatalf("put entry: %v", err)
+
+		return
+	}
+
+	if err := keyValue.Delete("a"); err != nil {
+		t.Fatalf("delete entry: %v", err)
+
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	var record sdk.Record
+	for _, want := range []struct {
+		operation sdk.Operation
+		payload   string
+	}{
+		{operation: sdk.OperationCreate, payload: "1"},
+		{operation: sdk.OperationUpdate, payload: "2"},
+		{operation: sdk.OperationDelete, payload: ""},
+	} {
+		record, err = readTestRecord(ctx, source)
+		if err != nil {
+			t.Fatalf("read record: %v", err)
+
+			return
+		}
+
+		if record.Operation != want.operation || string(record.Key.Bytes()) != "a" {
+			t.Fatalf("Source.Read = %s %s, want %s a", record.Operation, record.Key.Bytes(), want.operation)
+		}
+
+		if want.payload != "" && string(record.Payload.After.Bytes()) != want.payload {
+			t.Fatalf("Source.Read payload = %s, want %s", record.Payload.After.Bytes(), want.payload)
+		}
+	}
+
+	if err := source.Teardown(ctx); err != nil {
+		t.Fatalf("teardown source: %v", err)
+
+		return
+	}
+
+	if _, err := keyValue.Put("b", []byte("1")); err != nil {
+		t.Fatalf("put entry: %v", err)
+
+		return
+	}
+
+	// the restarted source skips the entries up to the position
+	source = NewSource()
+	if err := source.Configure(ctx, cfg); err != nil {
+		t.Fatalf("configure source: %v", err)
+
+		return
+	}
+
+	if err := source.Open(ctx, record.Position); err != nil {
+		t.Fatalf("open source: %v", err)
+
+		return
+	}
+
+	t.Cleanup(func() {
+		if err := source.Teardown(context.Background()); err != nil {
+			t.Fatalf("teardown source: %v", err)
+		}
+	})
+
+	record, err = readTestRecord(ctx, source)
+	if err != nil {
+		t.Fatalf("read record: %v", err)
 
 		return
 	}
+
+	if record.Operation != sdk.OperationCreate || string(record.Key.Bytes()) != "b" {
+		t.Fatalf("Source.Read = %s %s, want %s b", record.Operation, record.Key.Bytes(), sdk.OperationCreate)
+	}
 }
 
-func createTestJetStream(stream, subject string) (sdk.Source, error) {
+func TestSource_Read_ObjectStore(t *testing.T) {
+	t.Parallel()
+
+	testConn, err := test.GetTestConnection()
+	if err != nil {
+		t.Fatalf("get test connection: %v", err)
+
+		return
+	}
+
+	objectStore, err := test.CreateTestObjectStore(testConn, "files_read")
+	if err != nil {
+		t.Fatalf("create test object store: %v", err)
+
+		return
+	}
+
 	source := NewSource()
-	err := source.Configure(context.Background(), map[string]string{
+	if err := source.Configure(context.Background(), map[string]string{
+		config.KeyURLs:                test.TestURL,
+		config.KeyMode:                "object",
+		config.KeyObjectBucket:        "files_read",
+		ConfigKeyObjectMaxContentSize: "8",
+	}); err != nil {
+		t.Fatalf("configure source: %v", err)
+
+		return
+	}
+
+	if err := source.Open(context.Background(), nil); err != nil {
+		t.Fatalf("open source: %v", err)
+
+		return
+	}
+
+	t.Cleanup(func() {
+		if err := source.Teardown(context.Background()); err != nil {
+			t.Fatalf("teardown source: %v", err)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	// each change is read before the next one is made,
+	// since an object deleted before it's read is skipped
+	for _, step := range []struct {
+		change    func() error
+		operation sdk.Operation
+		key       string
+		reference bool
+		payload   string
+	}{
+		{
+			change: func() error {
+				_, err := objectStore.PutBytes("small", []byte("1"))
+
+				return err
+			},
+			operation: sdk.OperationCreate,
+			key:       "small",
+			payload:   "1",
+		},
+		{
+			change: func() error {
+				_, err := objectStore.PutBytes("large", []byte("0123456789"))
+
+				return err
+			},
+			operation: sdk.OperationCreate,
+			key:       "large",
+			reference: true,
+		},
+		{
+			change: func() error {
+				_, err := objectStore.PutBytes("small", []byte("2"))
+
+				return err
+			},
+			operation: sdk.OperationUpdate,
+			key:       "small",
+			payload:   "2",
+		},
+		{
+			change: func() error {
+				return objectStore.Delete("small")
+			},
+			operation: sdk.OperationDelete,
+			key:       "small",
+		},
+	} {
+		if err := step.change(); err != nil {
+			t.Fatalf("change object: %v", err)
+
+			return
+		}
+
+		record, err := readTestRecord(ctx, source)
+		if err != nil {
+			t.Fatalf("read record: %v", err)
+
+			return
+		}
+
+		if record.Operation != step.operation || string(record.Key.Bytes()) != step.key {
+			t.Fatalf("Source.Read = %s %s, want %s %s", record.Operation, record.Key.Bytes(), step.operation, step.key)
+		}
+
+		if step.reference {
+			// the object is larger than the max content size, so the record holds a reference to it
+			if _, ok := record.Payload.After.(sdk.StructuredData); !ok {
+				t.Fatalf("Source.Read payload = %s, want a reference", record.Payload.After.Bytes())
+			}
+
+			continue
+		}
+
+		if step.payload != "" && string(record.Payload.After.Bytes()) != step.payload {
+			t.Fatalf("Source.Read payload = %s, want %s", record.Payload.After.Bytes(), step.payload)
+		}
+	}
+}
+
+func TestSource_Read_Core(t *testing.T) {
+	t.Parallel()
+
+	testConn, err := test.GetTestConnection()
+	if err != nil {
+		t.Fatalf("get test connection: %v", err)
+
+		return
+	}
+
+	cfg := map[string]string{
+		config.KeyURLs:      test.TestURL,
+		config.KeyMode:      "core",
+		config.KeySubject:   "core_read.>",
+		ConfigKeyQueueGroup: "core_read",
+		ConfigKeyKeySource:  "subject:1",
+	}
+
+	// both sources subscribe in the same queue group, so each message is delivered to only one of them
+	sources := make([]sdk.Source, 2)
+	for i := range sources {
+		sources[i] = NewSource()
+		if err := sources[i].Configure(context.Background(), cfg); err != nil {
+			t.Fatalf("configure source: %v", err)
+
+			return
+		}
+
+		if err := sources[i].Open(context.Background(), nil); err != nil {
+			t.Fatalf("open source: %v", err)
+
+			return
+		}
+
+		source := sources[i]
+		t.Cleanup(func() {
+			if err := source.Teardown(context.Background()); err != nil {
+				t.Fatalf("teardown source: %v", err)
+			}
+		})
+	}
+
+	const count = 10
+
+	for i := 0; i < count; i++ {
+		if err := testConn.Publish(fmt.Sprintf("core_read.%d", i), []byte("payload")); err != nil {
+			t.Fatalf("publish message: %v", err)
+
+			return
+		}
+	}
+
+	if err := testConn.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+
+		return
+	}
+
+	// Read blocks while there are no records, so each source is drained once it waits for a second
+	keys := make(map[string]struct{})
+	for _, source := range sources {
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			record, err := source.Read(ctx)
+			cancel()
+
+			if errors.Is(err, context.DeadlineExceeded) {
+				break
+			}
+
+			if err != nil {
+				t.Fatalf("read record: %v", err)
+
+				return
+			}
+
+			if _, ok := keys[string(record.Key.Bytes())]; ok {
+				t.Fatalf("Source.Read key %s is delivered twice", record.Key.Bytes())
+			}
+
+			keys[string(record.Key.Bytes())] = struct{}{}
+		}
+	}
+
+	if len(keys) != count {
+		t.Fatalf("Source.Read got %d records, want %d", len(keys), count)
+	}
+}
+
+func createTestJetStream(stream, subject string) (sdk.Source, error) {
+	return createTestJetStreamWithConfig(stream, map[string]string{
 		config.KeyURLs:    test.TestURL,
 		config.KeySubject: subject,
 	})
+}
+
+func createTestJetStreamWithConfig(stream string, cfg map[string]string) (sdk.Source, error) {
+	source := NewSource()
+	err := source.Configure(context.Background(), cfg)
 	if err != nil {
 		return nil, fmt.Errorf("configure source: %v", err)
 	}
@@ -148,7 +1522,7 @@ func createTestJetStream(stream, subject string) (sdk.Source, error) {
 		return nil, fmt.Errorf("get test connection: %v", err)
 	}
 
-	err = test.CreateTestStream(testConn, stream, []string{subject})
+	err = test.CreateTestStream(testConn, stream, []string{cfg[config.KeySubject]})
 	if err != nil {
 		return nil, fmt.Errorf("add stream: %v", err)
 	}
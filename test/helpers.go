@@ -50,3 +50,38 @@ func CreateTestStream(conn *nats.Conn, streamName string, subjects []string) err
 
 	return nil
 }
+
+// CreateTestKeyValue creates a test Key-Value bucket.
+func CreateTestKeyValue(conn *nats.Conn, bucket string) (nats.KeyValue, error) {
+	jetstream, err := conn.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("get jetstream context: %w", err)
+	}
+
+	keyValue, err := jetstream.CreateKeyValue(&nats.KeyValueConfig{
+		Bucket:  bucket,
+		History: 10,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create key value: %w", err)
+	}
+
+	return keyValue, nil
+}
+
+// CreateTestObjectStore creates a test Object Store bucket.
+func CreateTestObjectStore(conn *nats.Conn, bucket string) (nats.ObjectStore, error) {
+	jetstream, err := conn.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("get jetstream context: %w", err)
+	}
+
+	objectStore, err := jetstream.CreateObjectStore(&nats.ObjectStoreConfig{
+		Bucket: bucket,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create object store: %w", err)
+	}
+
+	return objectStore, nil
+}